@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/internal/configs"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// importBatchSize is the number of cached translations sent per import request.
+const importBatchSize = 1000
+
+// cacheAdmin calls the cache administration API of a running server.
+type cacheAdmin struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newCacheAdmin(configPath string, serverURL string) (*cacheAdmin, error) {
+	config, err := configs.LoadConfig(configPath)
+	if err != nil {
+		logger.Error("Failed to load config", zap.Error(err))
+		return nil, err
+	}
+	if serverURL == "" {
+		host := config.Host
+		if host == "0.0.0.0" || host == "::" {
+			host = "localhost"
+		}
+		serverURL = fmt.Sprintf("http://%s:%d", host, config.Port)
+	}
+	if config.AdminToken == "" {
+		logger.Error("Admin token is not set, the admin API is disabled")
+		return nil, fmt.Errorf("admin_token is not set")
+	}
+	return &cacheAdmin{baseURL: serverURL + "/api/admin", token: config.AdminToken, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+func (a *cacheAdmin) do(method string, path string, query url.Values, body io.Reader) ([]byte, error) {
+	requestURL := a.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		logger.Error("Failed to call server", zap.String("URL", requestURL), zap.Error(err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
+
+func filterQuery(model string, from string, to string) url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{"model": model, "from": from, "to": to} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
+
+func newCacheCmd() *cobra.Command {
+	var serverURL string
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect, purge, export or import the cache of a running server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.PersistentFlags().StringVar(&serverURL, "url", "", "Server URL, defaults to the host and port of the config")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:          "stats <config_file_path>",
+		Short:        "Show the cache entries, hits and misses",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := newCacheAdmin(args[0], serverURL)
+			if err != nil {
+				return err
+			}
+			data, err := admin.do(http.MethodGet, "/cache/stats", nil, nil)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	})
+
+	var model, from, to string
+	addFilterFlags := func(c *cobra.Command) {
+		c.Flags().StringVar(&model, "model", "", "Only the translations of this model")
+		c.Flags().StringVar(&from, "from", "", "Only the translations from this language")
+		c.Flags().StringVar(&to, "to", "", "Only the translations to this language")
+	}
+
+	purgeCmd := &cobra.Command{
+		Use:          "purge <config_file_path>",
+		Short:        "Delete the cached translations",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := newCacheAdmin(args[0], serverURL)
+			if err != nil {
+				return err
+			}
+			data, err := admin.do(http.MethodDelete, "/cache", filterQuery(model, from, to), nil)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	}
+	addFilterFlags(purgeCmd)
+	cmd.AddCommand(purgeCmd)
+
+	var output, format string
+	exportCmd := &cobra.Command{
+		Use:          "export <config_file_path>",
+		Short:        "Export the cached translations as JSON lines, CSV or TMX",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := newCacheAdmin(args[0], serverURL)
+			if err != nil {
+				return err
+			}
+			query := filterQuery(model, from, to)
+			query.Set("format", exportFormat(format, output))
+			data, err := admin.do(http.MethodGet, "/cache/export", query, nil)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			logger.Info("Writing cache export", zap.String("Path", output))
+			return os.WriteFile(output, data, 0644)
+		},
+	}
+	addFilterFlags(exportCmd)
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "Output file, defaults to stdout")
+	exportCmd.Flags().StringVar(&format, "format", "", "Output format: jsonl, csv or tmx, defaults to the output extension")
+	cmd.AddCommand(exportCmd)
+
+	importCmd := &cobra.Command{
+		Use:          "import <config_file_path> <export_file_path>",
+		Short:        "Import cached translations to pre-seed the cache",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			admin, err := newCacheAdmin(args[0], serverURL)
+			if err != nil {
+				return err
+			}
+			file, err := os.Open(args[1])
+			if err != nil {
+				logger.Error("Failed to open import file", zap.String("Path", args[1]), zap.Error(err))
+				return err
+			}
+			defer file.Close()
+
+			imported, skipped := 0, 0
+			var batch bytes.Buffer
+			count := 0
+			send := func() error {
+				data, err := admin.do(http.MethodPost, "/cache/import", nil, bytes.NewReader(batch.Bytes()))
+				if err != nil {
+					return err
+				}
+				var result struct {
+					Imported int `json:"imported"`
+					Skipped  int `json:"skipped"`
+				}
+				if err := json.Unmarshal(data, &result); err != nil {
+					return err
+				}
+				imported += result.Imported
+				skipped += result.Skipped
+				batch.Reset()
+				return nil
+			}
+
+			// the translations are sent as JSON lines in batches, whatever the
+			// format of the file
+			encoder := json.NewEncoder(&batch)
+			err = client.ReadTranslations(exportFormat(format, args[1]), file, func(translation client.CachedTranslation) error {
+				if model != "" {
+					translation.Model = model
+				}
+				if err := encoder.Encode(translation); err != nil {
+					return err
+				}
+				if count++; count%importBatchSize == 0 {
+					return send()
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Error("Failed to import cache", zap.String("Path", args[1]), zap.Error(err))
+				return err
+			}
+			if batch.Len() > 0 {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "imported %d, skipped %d\n", imported, skipped)
+			return nil
+		},
+	}
+	importCmd.Flags().StringVar(&model, "model", "", "Import every translation into the cache of this model")
+	importCmd.Flags().StringVar(&format, "format", "", "File format: jsonl, csv or tmx, defaults to the file extension")
+	cmd.AddCommand(importCmd)
+	return cmd
+}
+
+// exportFormat returns the format given by flag, or else the one of the file
+// extension, or else JSON lines.
+func exportFormat(format string, path string) string {
+	if format != "" {
+		return format
+	}
+	if extension := strings.TrimPrefix(filepath.Ext(path), "."); client.IsExportFormat(extension) {
+		return extension
+	}
+	return "jsonl"
+}
@@ -36,6 +36,7 @@ func newRootCmd(version string, buildTime string, gitCommit string) *cobra.Comma
 	cmd.AddCommand(newRunCmd())
 	cmd.AddCommand(newGenCmd())
 	cmd.AddCommand(newValidCmd())
+	cmd.AddCommand(newCacheCmd())
 	return cmd
 }
 
@@ -1,12 +1,14 @@
 package configs
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
@@ -16,31 +18,138 @@ import (
 
 var logger = loggerPkg.GetLogger()
 
-var validModelTypes = []string{"openai"}
+var validCacheTypes = []string{"", "memory", "bbolt", "redis"}
+
+const defaultRedisPrefix = "polyglot:"
+
+var validModelTypes = []string{"openai", "anthropic", "gemini", "ollama", "llamacpp", "azure_openai", "deepl", "google_v2", "libretranslate", "http_template"}
+
+// nonLLMModelTypes are the translation APIs that take no prompt or generation settings
+var nonLLMModelTypes = []string{"deepl", "google_v2", "libretranslate", "http_template"}
+
+// modelTypesWithoutAPIKey are the model types that can be self-hosted without an API key
+var modelTypesWithoutAPIKey = []string{"ollama", "llamacpp", "libretranslate", "http_template"}
+
+const (
+	healthCheckTimeout     = 10 * time.Second
+	healthCheckPullTimeout = 5 * time.Minute
+)
 
 //go:embed config_example.toml
 var exampleConfigFs embed.FS
 
 type Config struct {
-	Port      int      `toml:"port"`
-	Host      string   `toml:"host"`
-	LogFile   string   `toml:"log_file"`
-	AuthToken []string `toml:"auth_token"`
-	Models    []Model  `toml:"models"`
+	Port       int      `toml:"port"`
+	Host       string   `toml:"host"`
+	LogFile    string   `toml:"log_file"`
+	AuthToken  []string `toml:"auth_token"`
+	AdminToken string   `toml:"admin_token"` // the /api/admin routes are not mounted when empty
+	Models     []Model  `toml:"models"`
+	Routes     []Route  `toml:"routes"`
+	Cache      Cache    `toml:"cache"`
+
+	TranslationMemory TranslationMemory `toml:"translation_memory"`
+	DeepL             DeepL             `toml:"deepl"`
+	Google            Google            `toml:"google"`
+	Hcfy              Hcfy              `toml:"hcfy"`
+}
+
+// Hcfy configures the selection translation endpoint.
+type Hcfy struct {
+	Hedge bool `toml:"hedge"` // hedge the requests of the models having hedge_with
+}
+
+// Google configures the endpoints compatible with the Google Cloud Translation
+// API v2.
+type Google struct {
+	Model string `toml:"model"` // model translating the requests, defaults to the routing rules
+}
+
+// DeepL configures the endpoints compatible with the DeepL API v2.
+type DeepL struct {
+	Model          string `toml:"model"`           // model translating the requests, defaults to the routing rules
+	CharacterLimit int64  `toml:"character_limit"` // characters translated before answering 456, 0 means no limit
+}
+
+// TranslationMemory answers the texts already translated and gives the
+// models the translations of similar texts as references.
+type TranslationMemory struct {
+	Enabled     bool     `toml:"enabled"`
+	Files       []string `toml:"files"`        // TMX files imported at startup
+	Threshold   float64  `toml:"threshold"`    // minimum similarity of the fuzzy matches, defaults to 0.75
+	MaxExamples int      `toml:"max_examples"` // fuzzy matches added to the prompt, defaults to 3
+	Learn       bool     `toml:"learn"`        // add the translations of the gateway to the memory
+}
+
+// Cache selects where the translations are cached. The memory cache is per
+// model and lost on restart, the bbolt cache is shared and kept on disk and
+// the redis cache is shared by several gateways.
+type Cache struct {
+	Type                   string `toml:"type"`                     // memory (default), bbolt or redis
+	Path                   string `toml:"path"`                     // bbolt only, database file
+	MaxEntries             int    `toml:"max_entries"`              // bbolt only, 0 means no limit
+	CompactIntervalMinutes int    `toml:"compact_interval_minutes"` // bbolt only, defaults to 60
+	URL                    string `toml:"url"`                      // redis only, e.g. redis://localhost:6379/0
+	Prefix                 string `toml:"prefix"`                   // redis only, defaults to polyglot:
+}
+
+// Route sends the translations of a language pair to a model, see client.Route.
+type Route struct {
+	From      string `toml:"from"`
+	To        string `toml:"to"`
+	Model     string `toml:"model"`
+	Priority  int    `toml:"priority"`
+	MinLength int    `toml:"min_length"`
+	MaxLength int    `toml:"max_length"`
 }
 
 type Model struct {
-	Name             string  `toml:"name"`
-	BaseURL          string  `toml:"base_url"`
-	Type             string  `toml:"type"`
-	APIKey           string  `toml:"api_key"`
-	ModelName        string  `toml:"model_name"`
-	MaxTokens        int     `toml:"max_tokens"`
-	Temperature      float32 `toml:"temperature"`
-	Prompt           string  `toml:"prompt"`
-	RateLimit        float64 `toml:"rate_limit"`
-	Endpoint         string  `toml:"endpoint"`
-	CacheExpireHours int     `toml:"cache_expire_hours"`
+	Name                 string            `toml:"name"`
+	BaseURL              string            `toml:"base_url"`
+	Type                 string            `toml:"type"`
+	APIKey               string            `toml:"api_key"`
+	ModelName            string            `toml:"model_name"`
+	MaxTokens            int               `toml:"max_tokens"`
+	Temperature          float32           `toml:"temperature"`
+	Prompt               string            `toml:"prompt"`
+	SystemPrompt         string            `toml:"system_prompt"`
+	RateLimit            float64           `toml:"rate_limit"`
+	Endpoint             string            `toml:"endpoint"`
+	CacheExpireHours     int               `toml:"cache_expire_hours"`
+	TimeoutSeconds       int               `toml:"timeout_seconds"`        // 0 means no timeout
+	NegativeCacheSeconds int               `toml:"negative_cache_seconds"` // how long refusals and rejected requests are cached, 0 means 300, -1 disables
+	Fallback             []string          `toml:"fallback"`               // models tried in order when this one fails
+	Members              []PoolMember      `toml:"members"`                // load balance over several API keys or base URLs
+	Balance              string            `toml:"balance"`                // round_robin, least_outstanding or weighted
+	MaxFailures          int               `toml:"max_failures"`           // consecutive failures before a member is ejected
+	EjectSeconds         int               `toml:"eject_seconds"`          // how long an ejected member is skipped
+	BreakerFailures      int               `toml:"breaker_failures"`       // consecutive failures that open the circuit breaker
+	BreakerErrorRate     float64           `toml:"breaker_error_rate"`     // error rate over the last 20 requests that opens the circuit breaker
+	BreakerOpenSeconds   int               `toml:"breaker_open_seconds"`   // how long the circuit breaker stays open before probing
+	RetryMaxAttempts     int               `toml:"retry_max_attempts"`     // attempts for 429, 5xx and network errors, 0 or 1 disables retries
+	RetryBaseDelayMs     int               `toml:"retry_base_delay_ms"`    // delay before the first retry, doubled on every attempt
+	RetryJitter          float64           `toml:"retry_jitter"`           // random fraction of the delay added or removed
+	HedgeWith            string            `toml:"hedge_with"`             // model raced against this one when it is slow
+	HedgePercentile      float64           `toml:"hedge_percentile"`       // latency percentile after which the hedge request is sent
+	HedgeDelayMs         int               `toml:"hedge_delay_ms"`         // minimum delay before the hedge request is sent
+	KeepAlive            string            `toml:"keep_alive"`             // ollama only
+	NumCtx               int               `toml:"num_ctx"`                // ollama only
+	AutoPull             bool              `toml:"auto_pull"`              // ollama only
+	Deployment           string            `toml:"deployment"`             // azure_openai only, defaults to model_name
+	APIVersion           string            `toml:"api_version"`            // azure_openai only
+	ADTokenFile          string            `toml:"ad_token_file"`          // azure_openai only, replaces api_key
+	Method               string            `toml:"method"`                 // http_template only, defaults to POST
+	Headers              map[string]string `toml:"headers"`                // http_template only
+	BodyTemplate         string            `toml:"body_template"`          // http_template only
+	ResponsePath         string            `toml:"response_path"`          // http_template only
+}
+
+// PoolMember overrides the API key, base URL or rate limit of a pooled model.
+type PoolMember struct {
+	APIKey    string  `toml:"api_key"`
+	BaseURL   string  `toml:"base_url"`
+	RateLimit float64 `toml:"rate_limit"`
+	Weight    int     `toml:"weight"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -81,6 +190,22 @@ func (c *Config) Validate() error {
 		logger.Error("Invalid host", zap.String("Host", c.Host))
 		return fmt.Errorf("invalid host: %s", c.Host)
 	}
+	if c.AdminToken != "" && slices.Contains(c.AuthToken, c.AdminToken) {
+		logger.Error("Invalid admin token, it is also an auth token")
+		return fmt.Errorf("invalid admin token: it is also an auth token")
+	}
+
+	if err := c.Cache.validate(); err != nil {
+		return err
+	}
+	if err := c.TranslationMemory.validate(); err != nil {
+		return err
+	}
+
+	modelNames := make([]string, 0, len(c.Models))
+	for _, model := range c.Models {
+		modelNames = append(modelNames, model.Name)
+	}
 
 	for _, model := range c.Models {
 		if model.Name == "" {
@@ -95,14 +220,10 @@ func (c *Config) Validate() error {
 			logger.Error("Invalid model type", zap.String("Type", model.Type))
 			return fmt.Errorf("invalid model type: %s", model.Type)
 		}
-		if model.APIKey == "" {
+		if model.APIKey == "" && model.requiresAPIKey() && len(model.Members) == 0 {
 			logger.Error("Invalid API key", zap.String("APIKey", model.APIKey))
 			return fmt.Errorf("invalid API key: %s", model.APIKey)
 		}
-		if model.ModelName == "" {
-			logger.Error("Invalid model name", zap.String("ModelName", model.ModelName))
-			return fmt.Errorf("invalid model name: %s", model.ModelName)
-		}
 		if model.RateLimit <= 0 {
 			logger.Error("Invalid rate limit", zap.Float64("RateLimit", model.RateLimit))
 			return fmt.Errorf("invalid rate limit: %f", model.RateLimit)
@@ -111,34 +232,184 @@ func (c *Config) Validate() error {
 			logger.Error("Invalid endpoint", zap.String("Endpoint", model.Endpoint))
 			return fmt.Errorf("invalid endpoint: %s", model.Endpoint)
 		}
-		if model.MaxTokens <= 0 {
-			logger.Error("Invalid max tokens", zap.Int("MaxTokens", model.MaxTokens))
-			return fmt.Errorf("invalid max tokens: %d", model.MaxTokens)
-		}
-		if model.Temperature < 0 || model.Temperature > 1 {
-			logger.Error("Invalid temperature", zap.Float32("Temperature", model.Temperature))
-			return fmt.Errorf("invalid temperature: %f", model.Temperature)
-		}
-		if model.Prompt == "" {
-			logger.Error("Invalid prompt", zap.String("Prompt", model.Prompt))
-			return fmt.Errorf("invalid prompt: %s", model.Prompt)
-		}
-		// check if prompt can be formatted
-		// 检查 Prompt 格式
-		expectedArgs := 3 // 期望的参数数量
-		if !isValidPromptFormat(model.Prompt, expectedArgs) {
-			logger.Error("Invalid prompt format", zap.String("Prompt", model.Prompt))
-			return fmt.Errorf("invalid prompt format: %s", model.Prompt)
+		if !slices.Contains(nonLLMModelTypes, model.Type) {
+			if err := validateLLMModel(model); err != nil {
+				return err
+			}
 		}
 
 		if model.CacheExpireHours <= 0 {
 			logger.Error("Invalid cache expire hours", zap.Int("CacheExpireHours", model.CacheExpireHours))
 			return fmt.Errorf("invalid cache expire hours: %d", model.CacheExpireHours)
 		}
+
+		if model.NumCtx < 0 {
+			logger.Error("Invalid num ctx", zap.Int("NumCtx", model.NumCtx))
+			return fmt.Errorf("invalid num ctx: %d", model.NumCtx)
+		}
+		if model.TimeoutSeconds < 0 {
+			logger.Error("Invalid timeout seconds", zap.Int("TimeoutSeconds", model.TimeoutSeconds))
+			return fmt.Errorf("invalid timeout seconds: %d", model.TimeoutSeconds)
+		}
+		if model.NegativeCacheSeconds < -1 {
+			logger.Error("Invalid negative cache seconds", zap.Int("NegativeCacheSeconds", model.NegativeCacheSeconds))
+			return fmt.Errorf("invalid negative cache seconds: %d", model.NegativeCacheSeconds)
+		}
+		if model.BreakerFailures < 0 || model.BreakerOpenSeconds < 0 {
+			logger.Error("Invalid circuit breaker", zap.Int("BreakerFailures", model.BreakerFailures), zap.Int("BreakerOpenSeconds", model.BreakerOpenSeconds))
+			return fmt.Errorf("invalid circuit breaker for model: %s", model.Name)
+		}
+		if model.BreakerErrorRate < 0 || model.BreakerErrorRate > 1 {
+			logger.Error("Invalid circuit breaker error rate", zap.Float64("BreakerErrorRate", model.BreakerErrorRate))
+			return fmt.Errorf("invalid circuit breaker error rate: %f", model.BreakerErrorRate)
+		}
+		if model.RetryMaxAttempts < 0 || model.RetryBaseDelayMs < 0 {
+			logger.Error("Invalid retry", zap.Int("RetryMaxAttempts", model.RetryMaxAttempts), zap.Int("RetryBaseDelayMs", model.RetryBaseDelayMs))
+			return fmt.Errorf("invalid retry for model: %s", model.Name)
+		}
+		if model.RetryJitter < 0 || model.RetryJitter > 1 {
+			logger.Error("Invalid retry jitter", zap.Float64("RetryJitter", model.RetryJitter))
+			return fmt.Errorf("invalid retry jitter: %f", model.RetryJitter)
+		}
+		for _, fallback := range model.Fallback {
+			if fallback == model.Name || !slices.Contains(modelNames, fallback) {
+				logger.Error("Invalid fallback", zap.String("Name", model.Name), zap.String("Fallback", fallback))
+				return fmt.Errorf("invalid fallback for model %s: %s", model.Name, fallback)
+			}
+		}
+
+		if len(model.Members) > 0 {
+			if err := validatePool(model); err != nil {
+				return err
+			}
+		}
+
+		if model.HedgeWith != "" {
+			if model.HedgeWith == model.Name || !slices.Contains(modelNames, model.HedgeWith) {
+				logger.Error("Invalid hedge model", zap.String("Name", model.Name), zap.String("HedgeWith", model.HedgeWith))
+				return fmt.Errorf("invalid hedge model for model %s: %s", model.Name, model.HedgeWith)
+			}
+			if model.HedgePercentile <= 0 || model.HedgePercentile > 100 {
+				logger.Error("Invalid hedge percentile", zap.Float64("HedgePercentile", model.HedgePercentile))
+				return fmt.Errorf("invalid hedge percentile: %f", model.HedgePercentile)
+			}
+			if model.HedgeDelayMs < 0 {
+				logger.Error("Invalid hedge delay", zap.Int("HedgeDelayMs", model.HedgeDelayMs))
+				return fmt.Errorf("invalid hedge delay: %d", model.HedgeDelayMs)
+			}
+		}
+
+		if model.Type == "http_template" && model.ResponsePath == "" {
+			logger.Error("Invalid response path", zap.String("Name", model.Name))
+			return fmt.Errorf("invalid response path for model: %s", model.Name)
+		}
+		if model.Type == "http_template" {
+			if err := client.ValidateHTTPTemplates(model.BaseURL, client.HTTPTemplateOptions{Headers: model.Headers, BodyTemplate: model.BodyTemplate}); err != nil {
+				logger.Error("Invalid http template", zap.String("Name", model.Name), zap.Error(err))
+				return fmt.Errorf("invalid http template for model %s: %w", model.Name, err)
+			}
+		}
+		if model.KeepAlive != "" && model.Type != "ollama" {
+			logger.Error("keep_alive is only supported by ollama", zap.String("Name", model.Name))
+			return fmt.Errorf("keep_alive is only supported by ollama: %s", model.Name)
+		}
+	}
+
+	if c.DeepL.Model != "" && !slices.Contains(modelNames, c.DeepL.Model) {
+		logger.Error("Invalid deepl model", zap.String("Model", c.DeepL.Model))
+		return fmt.Errorf("invalid deepl model: %s", c.DeepL.Model)
+	}
+	if c.DeepL.CharacterLimit < 0 {
+		logger.Error("Invalid deepl character limit", zap.Int64("CharacterLimit", c.DeepL.CharacterLimit))
+		return fmt.Errorf("invalid deepl character limit: %d", c.DeepL.CharacterLimit)
+	}
+	if c.Google.Model != "" && !slices.Contains(modelNames, c.Google.Model) {
+		logger.Error("Invalid google model", zap.String("Model", c.Google.Model))
+		return fmt.Errorf("invalid google model: %s", c.Google.Model)
+	}
+
+	for _, route := range c.Routes {
+		if !slices.Contains(modelNames, route.Model) {
+			logger.Error("Invalid route model", zap.String("Model", route.Model))
+			return fmt.Errorf("invalid route model: %s", route.Model)
+		}
+		if route.MinLength < 0 || route.MaxLength < 0 || (route.MaxLength > 0 && route.MinLength > route.MaxLength) {
+			logger.Error("Invalid route length", zap.Int("MinLength", route.MinLength), zap.Int("MaxLength", route.MaxLength))
+			return fmt.Errorf("invalid route length: %d-%d", route.MinLength, route.MaxLength)
+		}
+	}
+	return nil
+}
+
+// validateLLMModel checks the generation settings only LLM backends use.
+func validateLLMModel(model Model) error {
+	if model.ModelName == "" {
+		logger.Error("Invalid model name", zap.String("ModelName", model.ModelName))
+		return fmt.Errorf("invalid model name: %s", model.ModelName)
+	}
+	if model.MaxTokens <= 0 {
+		logger.Error("Invalid max tokens", zap.Int("MaxTokens", model.MaxTokens))
+		return fmt.Errorf("invalid max tokens: %d", model.MaxTokens)
+	}
+	if model.Temperature < 0 || model.Temperature > 1 {
+		logger.Error("Invalid temperature", zap.Float32("Temperature", model.Temperature))
+		return fmt.Errorf("invalid temperature: %f", model.Temperature)
+	}
+	if model.Prompt == "" {
+		logger.Error("Invalid prompt", zap.String("Prompt", model.Prompt))
+		return fmt.Errorf("invalid prompt: %s", model.Prompt)
+	}
+	// check if prompt can be formatted
+	// 检查 Prompt 格式
+	expectedArgs := 3 // 期望的参数数量
+	if !isValidPromptFormat(model.Prompt, expectedArgs) {
+		logger.Error("Invalid prompt format", zap.String("Prompt", model.Prompt))
+		return fmt.Errorf("invalid prompt format: %s", model.Prompt)
+	}
+	return nil
+}
+
+// validatePool checks the load balancing settings of a pooled model.
+func validatePool(model Model) error {
+	if model.Balance != "" && !slices.Contains(client.BalanceStrategies, model.Balance) {
+		logger.Error("Invalid balance strategy", zap.String("Balance", model.Balance))
+		return fmt.Errorf("invalid balance strategy: %s", model.Balance)
+	}
+	if model.MaxFailures < 0 {
+		logger.Error("Invalid max failures", zap.Int("MaxFailures", model.MaxFailures))
+		return fmt.Errorf("invalid max failures: %d", model.MaxFailures)
+	}
+	if model.EjectSeconds < 0 {
+		logger.Error("Invalid eject seconds", zap.Int("EjectSeconds", model.EjectSeconds))
+		return fmt.Errorf("invalid eject seconds: %d", model.EjectSeconds)
+	}
+	for _, member := range model.Members {
+		if member.APIKey == "" && model.APIKey == "" && model.requiresAPIKey() {
+			logger.Error("Invalid pool member API key", zap.String("Name", model.Name))
+			return fmt.Errorf("invalid API key for pool member of model: %s", model.Name)
+		}
+		if member.RateLimit < 0 {
+			logger.Error("Invalid pool member rate limit", zap.Float64("RateLimit", member.RateLimit))
+			return fmt.Errorf("invalid rate limit for pool member of model %s: %f", model.Name, member.RateLimit)
+		}
+		if member.Weight < 0 {
+			logger.Error("Invalid pool member weight", zap.Int("Weight", member.Weight))
+			return fmt.Errorf("invalid weight for pool member of model %s: %d", model.Name, member.Weight)
+		}
 	}
 	return nil
 }
 
+func (m Model) requiresAPIKey() bool {
+	if slices.Contains(modelTypesWithoutAPIKey, m.Type) {
+		return false
+	}
+	if m.Type == "azure_openai" && m.ADTokenFile != "" {
+		return false
+	}
+	return true
+}
+
 func isValidPromptFormat(format string, expectedArgs int) bool {
 	// 使用更灵活的方法来验证格式字符串
 	placeholders := []string{"%s", "%d", "%f", "%v"}
@@ -174,24 +445,308 @@ func GenerateExampleConfig(filePath string) error {
 	return os.WriteFile(filePath, exampleConfig, 0644)
 }
 
-func CreateClientManager(models []Model) *client.ClientManager {
+func (c Cache) validate() error {
+	if !slices.Contains(validCacheTypes, c.Type) {
+		logger.Error("Invalid cache type", zap.String("Type", c.Type))
+		return fmt.Errorf("invalid cache type: %s", c.Type)
+	}
+	if c.Type == "bbolt" && c.Path == "" {
+		logger.Error("Invalid cache path", zap.String("Path", c.Path))
+		return fmt.Errorf("invalid cache path: %s", c.Path)
+	}
+	if c.Type == "redis" && c.URL == "" {
+		logger.Error("Invalid cache url", zap.String("URL", c.URL))
+		return fmt.Errorf("invalid cache url: %s", c.URL)
+	}
+	if c.MaxEntries < 0 {
+		logger.Error("Invalid cache max entries", zap.Int("MaxEntries", c.MaxEntries))
+		return fmt.Errorf("invalid cache max entries: %d", c.MaxEntries)
+	}
+	if c.CompactIntervalMinutes < 0 {
+		logger.Error("Invalid cache compact interval", zap.Int("CompactIntervalMinutes", c.CompactIntervalMinutes))
+		return fmt.Errorf("invalid cache compact interval: %d", c.CompactIntervalMinutes)
+	}
+	return nil
+}
+
+func (tm TranslationMemory) validate() error {
+	if tm.Threshold < 0 || tm.Threshold > 1 {
+		logger.Error("Invalid translation memory threshold", zap.Float64("Threshold", tm.Threshold))
+		return fmt.Errorf("invalid translation memory threshold: %f", tm.Threshold)
+	}
+	if tm.MaxExamples < 0 {
+		logger.Error("Invalid translation memory max examples", zap.Int("MaxExamples", tm.MaxExamples))
+		return fmt.Errorf("invalid translation memory max examples: %d", tm.MaxExamples)
+	}
+	for _, file := range tm.Files {
+		if _, err := os.Stat(file); err != nil {
+			logger.Error("Invalid translation memory file", zap.String("Path", file), zap.Error(err))
+			return fmt.Errorf("invalid translation memory file: %s", file)
+		}
+	}
+	return nil
+}
+
+// CreateTranslationMemory creates the translation memory and imports its TMX
+// files, or returns nil when it is disabled.
+func CreateTranslationMemory(config TranslationMemory) (*client.TranslationMemory, client.TMOptions, error) {
+	options := client.TMOptions{
+		Threshold:   config.Threshold,
+		MaxExamples: config.MaxExamples,
+		Learn:       config.Learn,
+	}
+	if options.Threshold == 0 {
+		options.Threshold = 0.75
+	}
+	if options.MaxExamples == 0 {
+		options.MaxExamples = 3
+	}
+	if !config.Enabled {
+		return nil, options, nil
+	}
+
+	tm := client.NewTranslationMemory()
+	for _, path := range config.Files {
+		file, err := os.Open(path)
+		if err != nil {
+			logger.Error("Failed to open translation memory file", zap.String("Path", path), zap.Error(err))
+			return nil, options, err
+		}
+		imported, err := tm.ImportTMX(file)
+		file.Close()
+		if err != nil {
+			logger.Error("Failed to import translation memory file", zap.String("Path", path), zap.Error(err))
+			return nil, options, err
+		}
+		logger.Info("Translation memory imported", zap.String("Path", path), zap.Int("Segments", imported))
+	}
+	return tm, options, nil
+}
+
+// CreateCache creates the cache shared by all the models, or returns nil when
+// every model keeps its own memory cache.
+func CreateCache(config Cache) (client.Cache, error) {
+	switch config.Type {
+	case "bbolt":
+		if dir := filepath.Dir(config.Path); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				logger.Error("Failed to create cache directory", zap.String("Path", dir), zap.Error(err))
+				return nil, err
+			}
+		}
+		cache, err := client.NewBoltCache(client.BoltCacheOptions{
+			Path:            config.Path,
+			MaxEntries:      config.MaxEntries,
+			CompactInterval: time.Duration(config.CompactIntervalMinutes) * time.Minute,
+		})
+		if err != nil {
+			logger.Error("Failed to open cache", zap.String("Path", config.Path), zap.Error(err))
+			return nil, err
+		}
+		logger.Info("Using bbolt cache", zap.String("Path", config.Path))
+		return cache, nil
+	case "redis":
+		prefix := config.Prefix
+		if prefix == "" {
+			prefix = defaultRedisPrefix
+		}
+		cache, err := client.NewRedisCache(client.RedisCacheOptions{URL: config.URL, Prefix: prefix})
+		if err != nil {
+			logger.Error("Failed to create redis cache", zap.Error(err))
+			return nil, err
+		}
+		logger.Info("Using redis cache", zap.String("Prefix", prefix))
+		return cache, nil
+	default:
+		return nil, nil
+	}
+}
+
+// CreateClientManager creates the clients of the models. When cache is not
+// nil, it replaces the memory cache of every client.
+func CreateClientManager(models []Model, cache client.Cache) (*client.ClientManager, error) {
 	clientManager := client.NewClientManager()
 	for _, model := range models {
-		if model.Type == "openai" {
-			client := client.NewOpenAIClient(client.ClientInfo{
-				Name:             model.Name,
-				BaseURL:          model.BaseURL,
-				Endpoint:         model.Endpoint,
-				ModelName:        model.ModelName,
-				MaxTokens:        model.MaxTokens,
-				Temperature:      model.Temperature,
-				Prompt:           model.Prompt,
-				RateLimit:        model.RateLimit,
-				CacheExpireHours: model.CacheExpireHours,
-			}, model.APIKey)
-			logger.Debug("Adding client", zap.String("ModelName", model.Name), zap.String("Endpoint", model.Endpoint))
-			clientManager.AddClient(model.Endpoint, client)
-		}
-	}
-	return clientManager
+		var modelClient client.Client
+		var err error
+		if len(model.Members) > 0 {
+			modelClient, err = newPoolClient(model)
+		} else {
+			modelClient, err = newClient(model)
+		}
+		if err != nil {
+			logger.Error("Failed to create client", zap.String("ModelName", model.Name), zap.Error(err))
+			return nil, fmt.Errorf("failed to create client %s: %w", model.Name, err)
+		}
+		if setter, ok := modelClient.(client.CacheHolder); ok && cache != nil {
+			setter.SetCache(cache)
+		}
+
+		logger.Debug("Adding client", zap.String("ModelName", model.Name), zap.String("Endpoint", model.Endpoint))
+		clientManager.AddClient(model.Endpoint, modelClient)
+		if len(model.Fallback) > 0 {
+			clientManager.SetFallbacks(model.Name, model.Fallback)
+		}
+		if model.HedgeWith != "" {
+			clientManager.SetHedge(model.Name, client.HedgeOptions{
+				Secondary:  model.HedgeWith,
+				Percentile: model.HedgePercentile,
+				MinDelay:   time.Duration(model.HedgeDelayMs) * time.Millisecond,
+			})
+		}
+	}
+	return clientManager, nil
+}
+
+func CreateRoutes(routes []Route) []client.Route {
+	clientRoutes := make([]client.Route, 0, len(routes))
+	for _, route := range routes {
+		clientRoutes = append(clientRoutes, client.Route{
+			From:      route.From,
+			To:        route.To,
+			Model:     route.Model,
+			Priority:  route.Priority,
+			MinLength: route.MinLength,
+			MaxLength: route.MaxLength,
+		})
+	}
+	return clientRoutes
+}
+
+func newClientInfo(model Model) client.ClientInfo {
+	return client.ClientInfo{
+		Name:             model.Name,
+		BaseURL:          model.BaseURL,
+		Endpoint:         model.Endpoint,
+		ModelName:        model.ModelName,
+		MaxTokens:        model.MaxTokens,
+		Temperature:      model.Temperature,
+		Prompt:           model.Prompt,
+		SystemPrompt:     model.SystemPrompt,
+		RateLimit:        model.RateLimit,
+		CacheExpireHours: model.CacheExpireHours,
+		Timeout:          time.Duration(model.TimeoutSeconds) * time.Second,
+		NegativeCacheTTL: time.Duration(model.NegativeCacheSeconds) * time.Second,
+		Breaker: client.BreakerOptions{
+			MaxFailures:  model.BreakerFailures,
+			ErrorRate:    model.BreakerErrorRate,
+			OpenDuration: time.Duration(model.BreakerOpenSeconds) * time.Second,
+		},
+		Retry: client.RetryOptions{
+			MaxAttempts: model.RetryMaxAttempts,
+			BaseDelay:   time.Duration(model.RetryBaseDelayMs) * time.Millisecond,
+			Jitter:      model.RetryJitter,
+		},
+	}
+}
+
+func newClient(model Model) (client.Client, error) {
+	info := newClientInfo(model)
+
+	var modelClient client.Client
+	switch model.Type {
+	case "openai":
+		modelClient = client.NewOpenAIClient(info, model.APIKey)
+	case "anthropic":
+		modelClient = client.NewAnthropicClient(info, model.APIKey)
+	case "gemini":
+		modelClient = client.NewGeminiClient(info, model.APIKey)
+	case "ollama":
+		modelClient = client.NewOllamaClient(info, client.OllamaOptions{
+			KeepAlive: model.KeepAlive,
+			NumCtx:    model.NumCtx,
+			AutoPull:  model.AutoPull,
+		})
+	case "llamacpp":
+		modelClient = client.NewLlamaCppClient(info)
+	case "azure_openai":
+		azureClient, err := client.NewAzureOpenAIClient(info, client.AzureOptions{
+			APIKey:      model.APIKey,
+			Deployment:  model.Deployment,
+			APIVersion:  model.APIVersion,
+			ADTokenFile: model.ADTokenFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure OpenAI client: %w", err)
+		}
+		modelClient = azureClient
+	case "deepl":
+		modelClient = client.NewDeepLClient(info, model.APIKey)
+	case "google_v2":
+		modelClient = client.NewGoogleV2Client(info, model.APIKey)
+	case "libretranslate":
+		modelClient = client.NewLibreTranslateClient(info, model.APIKey)
+	case "http_template":
+		templateClient, err := client.NewHTTPTemplateClient(info, client.HTTPTemplateOptions{
+			APIKey:       model.APIKey,
+			Method:       model.Method,
+			Headers:      model.Headers,
+			BodyTemplate: model.BodyTemplate,
+			ResponsePath: model.ResponsePath,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP template client: %w", err)
+		}
+		modelClient = templateClient
+	default:
+		return nil, fmt.Errorf("unsupported model type: %s", model.Type)
+	}
+
+	if healthChecker, ok := modelClient.(client.HealthChecker); ok {
+		checkHealth(model, healthChecker)
+	}
+	return modelClient, nil
+}
+
+// newPoolClient creates one client per pool member, each member overriding
+// the API key, base URL or rate limit of the model.
+func newPoolClient(model Model) (client.Client, error) {
+	members := make([]*client.PoolMember, 0, len(model.Members))
+	for _, member := range model.Members {
+		memberModel := model
+		if member.APIKey != "" {
+			memberModel.APIKey = member.APIKey
+		}
+		if member.BaseURL != "" {
+			memberModel.BaseURL = member.BaseURL
+		}
+		if member.RateLimit > 0 {
+			memberModel.RateLimit = member.RateLimit
+		}
+
+		memberClient, err := newClient(memberModel)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &client.PoolMember{Client: memberClient, Weight: member.Weight})
+	}
+
+	return client.NewPoolClient(newClientInfo(model), members, client.PoolOptions{
+		Strategy:      model.Balance,
+		MaxFailures:   model.MaxFailures,
+		EjectDuration: time.Duration(model.EjectSeconds) * time.Second,
+	})
+}
+
+// checkHealth runs the startup check of a local model. A failing check is only
+// logged since the upstream may come up after the gateway.
+func checkHealth(model Model, healthChecker client.HealthChecker) {
+	timeout := healthCheckTimeout
+	if model.AutoPull {
+		// pulling a model takes much longer than a plain health check
+		timeout = healthCheckPullTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := healthChecker.CheckHealth(ctx); err != nil {
+		logger.Error("Model health check failed",
+			zap.String("Name", model.Name),
+			zap.String("ModelName", model.ModelName),
+			zap.String("BaseURL", model.BaseURL),
+			zap.Error(err),
+		)
+		return
+	}
+	logger.Info("Model health check passed", zap.String("Name", model.Name), zap.String("ModelName", model.ModelName))
 }
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+var exportContentTypes = map[string]string{
+	"jsonl": "application/x-ndjson",
+	"csv":   "text/csv; charset=utf-8",
+	"tmx":   "application/x-tmx+xml",
+}
+
+func cacheFilterFromQuery(ctx *fiber.Ctx) client.CacheFilter {
+	return client.CacheFilter{
+		Model: ctx.Query("model"),
+		From:  ctx.Query("from"),
+		To:    ctx.Query("to"),
+	}
+}
+
+// addCacheAdminRoutes adds the routes inspecting and purging the cache, the
+// exports and imports are in one of client.ExportFormats.
+func addCacheAdminRoutes(admin fiber.Router, clientManager *client.ClientManager) {
+	admin.Get("/cache/stats", func(ctx *fiber.Ctx) error {
+		stats, err := clientManager.CacheStats()
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get cache stats"})
+		}
+		hitRate := 0.0
+		if lookups := stats.Hits + stats.Misses; lookups > 0 {
+			hitRate = float64(stats.Hits) / float64(lookups)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"entries":  stats.Entries,
+			"hits":     stats.Hits,
+			"misses":   stats.Misses,
+			"hit_rate": hitRate,
+		})
+	})
+
+	admin.Delete("/cache", func(ctx *fiber.Ctx) error {
+		purged, err := clientManager.PurgeCache(cacheFilterFromQuery(ctx))
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to purge cache", "purged": purged})
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"purged": purged})
+	})
+
+	admin.Get("/cache/lookup", func(ctx *fiber.Ctx) error {
+		filter := cacheFilterFromQuery(ctx)
+		text := ctx.Query("text")
+		if filter.Model == "" || filter.From == "" || filter.To == "" || text == "" {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+		translation, found, err := clientManager.LookupCache(filter.Model, text, filter.From, filter.To)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !found {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not cached"})
+		}
+		return ctx.Status(fiber.StatusOK).JSON(translation)
+	})
+
+	admin.Get("/cache/export", func(ctx *fiber.Ctx) error {
+		format := ctx.Query("format", "jsonl")
+		var body bytes.Buffer
+		writer, err := client.NewTranslationWriter(format, &body)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		err = clientManager.ExportCache(cacheFilterFromQuery(ctx), writer.Write)
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			logger.Error("Failed to export cache", zap.Error(err))
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export cache"})
+		}
+		ctx.Set(fiber.HeaderContentType, exportContentTypes[format])
+		return ctx.Status(fiber.StatusOK).Send(body.Bytes())
+	})
+
+	// the model parameter imports every translation into the cache of that model
+	admin.Post("/cache/import", func(ctx *fiber.Ctx) error {
+		format, model := ctx.Query("format", "jsonl"), ctx.Query("model")
+		if !client.IsExportFormat(format) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported format"})
+		}
+		imported, skipped := 0, 0
+		err := client.ReadTranslations(format, bytes.NewReader(ctx.Body()), func(translation client.CachedTranslation) error {
+			if model != "" {
+				translation.Model = model
+			}
+			if err := clientManager.ImportCache(translation); err != nil {
+				logger.Warn("Skipping cached translation", zap.Error(err), zap.String("Model", translation.Model))
+				skipped++
+				return nil
+			}
+			imported++
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to import cache", zap.Error(err))
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "imported": imported})
+		}
+		logger.Info("Cache imported", zap.Int("Imported", imported), zap.Int("Skipped", skipped))
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"imported": imported, "skipped": skipped})
+	})
+}
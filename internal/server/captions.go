@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+const (
+	// captionsMaxInFlight is the number of utterances of a connection being
+	// translated at the same time, the connection is not read beyond it so
+	// that a caller sending faster than the models are rate limited to is
+	// slowed down.
+	captionsMaxInFlight = 16
+	// captionsAuthTimeout is how long a connection has to send its auth
+	// message.
+	captionsAuthTimeout = 10 * time.Second
+	// captionsWriteTimeout drops the callers that stopped reading.
+	captionsWriteTimeout = 10 * time.Second
+)
+
+// CaptionRequest is a message sent on the captions channel, either the auth
+// message or an utterance to translate.
+type CaptionRequest struct {
+	Type         string `json:"type"` // auth or translate
+	Token        string `json:"token"`
+	ID           string `json:"id"`
+	Text         string `json:"text"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	ModelName    string `json:"model_name"` // empty uses the routing rules
+	Stream       bool   `json:"stream"`     // send the partial translations as delta messages
+	ForceRefresh bool   `json:"force_refresh"`
+}
+
+// CaptionResponse is a message sent back on the captions channel.
+type CaptionResponse struct {
+	Type           string `json:"type"` // ready, delta or result
+	ID             string `json:"id,omitempty"`
+	Text           string `json:"text,omitempty"`
+	ModelName      string `json:"model_name,omitempty"`
+	TranslatedText string `json:"translated_text,omitempty"`
+}
+
+// captionError flattens the body answering a failed translation into an
+// error message of the captions channel.
+func captionError(id string, status int, body any) fiber.Map {
+	message := fiber.Map{"type": "error", "id": id, "status": status}
+	switch body := body.(type) {
+	case RefusalResponse:
+		message["error"] = body.Error
+		message["refusal"] = body.Refusal
+		message["cached"] = body.Cached
+	case fiber.Map:
+		maps.Copy(message, body)
+	}
+	return message
+}
+
+type captionSession struct {
+	conn          *websocket.Conn
+	clientManager *client.ClientManager
+	writeMu       sync.Mutex
+}
+
+// send writes a message, the translations of a connection finish
+// concurrently.
+func (s *captionSession) send(message any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.SetWriteDeadline(time.Now().Add(captionsWriteTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(message)
+}
+
+// authenticate reads the auth message of a connection that was not
+// authenticated when upgraded.
+func (s *captionSession) authenticate(authTokens []string) bool {
+	if err := s.conn.SetReadDeadline(time.Now().Add(captionsAuthTimeout)); err != nil {
+		return false
+	}
+	var request CaptionRequest
+	if err := s.conn.ReadJSON(&request); err != nil || request.Type != "auth" || !slices.Contains(authTokens, request.Token) {
+		_ = s.send(captionError("", fiber.StatusUnauthorized, fiber.Map{"error": "Unauthorized"}))
+		_ = s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "Unauthorized"))
+		return false
+	}
+	return s.conn.SetReadDeadline(time.Time{}) == nil
+}
+
+// serve reads the utterances until the connection is closed and translates
+// them concurrently, the results are sent as soon as they are ready and may
+// be out of order.
+func (s *captionSession) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// the caller went away, the pending translations are of no use
+	defer cancel()
+
+	if err := s.send(CaptionResponse{Type: "ready"}); err != nil {
+		return
+	}
+
+	inFlight := make(chan struct{}, captionsMaxInFlight)
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.Debug("Captions connection closed", zap.Error(err))
+			}
+			return
+		}
+
+		var request CaptionRequest
+		if err := json.Unmarshal(data, &request); err != nil || request.Type != "translate" ||
+			request.ID == "" || request.From == "" || request.To == "" || request.Text == "" {
+			if s.send(captionError(request.ID, fiber.StatusBadRequest, fiber.Map{"error": "Invalid request"})) != nil {
+				return
+			}
+			continue
+		}
+
+		modelClient, message := s.modelClient(request)
+		if modelClient == nil {
+			if s.send(captionError(request.ID, fiber.StatusBadRequest, fiber.Map{"error": message})) != nil {
+				return
+			}
+			continue
+		}
+
+		// every slot waits for the rate limiter of its model, stop reading
+		// until one of them is done
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			s.translate(ctx, request, modelClient)
+		}()
+	}
+}
+
+// modelClient returns the client translating the utterance, or the error
+// answered when there is none.
+func (s *captionSession) modelClient(request CaptionRequest) (client.Client, string) {
+	if request.ModelName == "" {
+		modelClient, err := s.clientManager.Route(request.Text, request.From, request.To)
+		if err != nil {
+			return nil, "No route found"
+		}
+		return modelClient, ""
+	}
+	modelClient, err := s.clientManager.GetClientByName(request.ModelName)
+	if err != nil {
+		return nil, "Client not found"
+	}
+	return modelClient, ""
+}
+
+func (s *captionSession) translate(ctx context.Context, request CaptionRequest, modelClient client.Client) {
+	var translatedText string
+	var usedClient client.Client
+	var err error
+	if request.Stream {
+		translatedText, usedClient, err = s.clientManager.CompleteStream(ctx, modelClient, request.Text, request.From, request.To, request.ForceRefresh, func(delta string) error {
+			return s.send(CaptionResponse{Type: "delta", ID: request.ID, Text: delta})
+		})
+	} else {
+		translatedText, usedClient, err = s.clientManager.Complete(ctx, modelClient, request.Text, request.From, request.To, request.ForceRefresh)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Error translating caption", zap.String("ID", request.ID), zap.String("ModelName", modelClient.GetClientInfo().Name), zap.Error(err))
+		status, body := translationError(err)
+		_ = s.send(captionError(request.ID, status, body))
+		return
+	}
+
+	_ = s.send(CaptionResponse{
+		Type:           "result",
+		ID:             request.ID,
+		ModelName:      usedClient.GetClientInfo().Name,
+		TranslatedText: translatedText,
+	})
+}
+
+// addCaptionsRoutes adds the websocket channel translating live captions. A
+// caller authenticates once, with the Authorization header of the upgrade
+// request or, since browsers cannot set it, with an auth message, and then
+// sends its utterances over the same connection.
+func addCaptionsRoutes(app *fiber.App, clientManager *client.ClientManager, authTokens []string) {
+	app.Use("/ws/captions", func(ctx *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(ctx) {
+			return ctx.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{"error": "Upgrade required"})
+		}
+		if authHeader := ctx.Get(fiber.HeaderAuthorization); authHeader != "" {
+			token, found := strings.CutPrefix(authHeader, "Bearer ")
+			if !found || !slices.Contains(authTokens, token) {
+				return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+			}
+			ctx.Locals("authenticated", true)
+		}
+		return ctx.Next()
+	})
+
+	app.Get("/ws/captions", websocket.New(func(conn *websocket.Conn) {
+		session := &captionSession{conn: conn, clientManager: clientManager}
+		if authenticated, _ := conn.Locals("authenticated").(bool); !authenticated && !session.authenticate(authTokens) {
+			return
+		}
+		session.serve()
+	}))
+}
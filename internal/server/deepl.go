@@ -0,0 +1,330 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/internal/configs"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+// deepLMaxTexts is the number of texts DeepL accepts in a single request.
+const deepLMaxTexts = 50
+
+// deepLUnlimited is the character limit reported when there is none.
+const deepLUnlimited = 1_000_000_000_000
+
+// errNoDeepLModel is returned when no model is configured for the DeepL API
+// and no routing rule matches.
+var errNoDeepLModel = errors.New("no model for the language pair")
+
+// deepLLanguage is a language of the DeepL API, Language being the name
+// passed to the models.
+type deepLLanguage struct {
+	Code              string
+	Name              string
+	Language          string
+	SupportsFormality bool
+}
+
+var deepLSourceLanguages = []deepLLanguage{
+	{Code: "AR", Name: "Arabic", Language: "Arabic"},
+	{Code: "BG", Name: "Bulgarian", Language: "Bulgarian"},
+	{Code: "CS", Name: "Czech", Language: "Czech"},
+	{Code: "DA", Name: "Danish", Language: "Danish"},
+	{Code: "DE", Name: "German", Language: "German", SupportsFormality: true},
+	{Code: "EL", Name: "Greek", Language: "Greek"},
+	{Code: "EN", Name: "English", Language: "English"},
+	{Code: "ES", Name: "Spanish", Language: "Spanish", SupportsFormality: true},
+	{Code: "ET", Name: "Estonian", Language: "Estonian"},
+	{Code: "FI", Name: "Finnish", Language: "Finnish"},
+	{Code: "FR", Name: "French", Language: "French", SupportsFormality: true},
+	{Code: "HU", Name: "Hungarian", Language: "Hungarian"},
+	{Code: "ID", Name: "Indonesian", Language: "Indonesian"},
+	{Code: "IT", Name: "Italian", Language: "Italian", SupportsFormality: true},
+	{Code: "JA", Name: "Japanese", Language: "Japanese", SupportsFormality: true},
+	{Code: "KO", Name: "Korean", Language: "Korean"},
+	{Code: "LT", Name: "Lithuanian", Language: "Lithuanian"},
+	{Code: "LV", Name: "Latvian", Language: "Latvian"},
+	{Code: "NB", Name: "Norwegian (Bokmål)", Language: "Norwegian"},
+	{Code: "NL", Name: "Dutch", Language: "Dutch", SupportsFormality: true},
+	{Code: "PL", Name: "Polish", Language: "Polish", SupportsFormality: true},
+	{Code: "PT", Name: "Portuguese", Language: "Portuguese", SupportsFormality: true},
+	{Code: "RO", Name: "Romanian", Language: "Romanian"},
+	{Code: "RU", Name: "Russian", Language: "Russian", SupportsFormality: true},
+	{Code: "SK", Name: "Slovak", Language: "Slovak"},
+	{Code: "SL", Name: "Slovenian", Language: "Slovenian"},
+	{Code: "SV", Name: "Swedish", Language: "Swedish"},
+	{Code: "TR", Name: "Turkish", Language: "Turkish"},
+	{Code: "UK", Name: "Ukrainian", Language: "Ukrainian"},
+	{Code: "ZH", Name: "Chinese", Language: "Chinese(Simplified)"},
+}
+
+// deepLTargetLanguages are the source languages with the English, Portuguese
+// and Chinese variants. The plain EN, PT and ZH codes are still accepted as
+// target languages.
+var deepLTargetLanguages = func() []deepLLanguage {
+	variants := map[string][]deepLLanguage{
+		"EN": {
+			{Code: "EN-GB", Name: "English (British)", Language: "British English"},
+			{Code: "EN-US", Name: "English (American)", Language: "American English"},
+		},
+		"PT": {
+			{Code: "PT-BR", Name: "Portuguese (Brazilian)", Language: "Brazilian Portuguese", SupportsFormality: true},
+			{Code: "PT-PT", Name: "Portuguese (European)", Language: "European Portuguese", SupportsFormality: true},
+		},
+		"ZH": {
+			{Code: "ZH-HANS", Name: "Chinese (simplified)", Language: "Chinese(Simplified)"},
+			{Code: "ZH-HANT", Name: "Chinese (traditional)", Language: "Chinese(Traditional)"},
+		},
+	}
+	languages := make([]deepLLanguage, 0, len(deepLSourceLanguages)+3)
+	for _, language := range deepLSourceLanguages {
+		if variants, ok := variants[language.Code]; ok {
+			languages = append(languages, variants...)
+		} else {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}()
+
+func findDeepLLanguage(languages []deepLLanguage, code string) (deepLLanguage, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, language := range languages {
+		if language.Code == code {
+			return language, true
+		}
+	}
+	return deepLLanguage{}, false
+}
+
+// deepLLanguageCode converts a canonical language code to a DeepL source
+// language code.
+func deepLLanguageCode(code string) string {
+	base, _, _ := strings.Cut(code, "-")
+	if language, ok := findDeepLLanguage(deepLSourceLanguages, base); ok {
+		return language.Code
+	}
+	return strings.ToUpper(base)
+}
+
+// DeepLTranslateRequest is the request of the DeepL API v2, sent as JSON or
+// form-encoded with repeated text parameters. split_sentences,
+// preserve_formatting and glossary_id are accepted and ignored.
+type DeepLTranslateRequest struct {
+	Text        []string `json:"text" form:"text"`
+	SourceLang  string   `json:"source_lang" form:"source_lang"`
+	TargetLang  string   `json:"target_lang" form:"target_lang"`
+	Formality   string   `json:"formality" form:"formality"`
+	TagHandling string   `json:"tag_handling" form:"tag_handling"`
+}
+
+type DeepLTranslation struct {
+	DetectedSourceLanguage string `json:"detected_source_language"`
+	Text                   string `json:"text"`
+}
+
+type DeepLTranslateResponse struct {
+	Translations []DeepLTranslation `json:"translations"`
+}
+
+type DeepLUsageResponse struct {
+	CharacterCount int64 `json:"character_count"`
+	CharacterLimit int64 `json:"character_limit"`
+}
+
+type DeepLLanguageResponse struct {
+	Language          string `json:"language"`
+	Name              string `json:"name"`
+	SupportsFormality *bool  `json:"supports_formality,omitempty"`
+}
+
+var (
+	deepLFormalities  = []string{"", "default", "more", "less", "prefer_more", "prefer_less"}
+	deepLTagHandlings = []string{"", "xml", "html"}
+)
+
+// sendDeepLError answers with the error body of the DeepL API.
+func sendDeepLError(ctx *fiber.Ctx, status int, message string) error {
+	return ctx.Status(status).JSON(fiber.Map{"message": message})
+}
+
+// deepLAuthMiddleware accepts the auth tokens sent as the DeepL-Auth-Key or
+// Bearer authorization, or as the deprecated auth_key parameter.
+func deepLAuthMiddleware(authTokens []string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		authHeader := ctx.Get(fiber.HeaderAuthorization)
+		token, found := strings.CutPrefix(authHeader, "DeepL-Auth-Key ")
+		if !found {
+			token, found = strings.CutPrefix(authHeader, "Bearer ")
+		}
+		if !found {
+			token = ctx.FormValue("auth_key", ctx.Query("auth_key"))
+		}
+		if token == "" || !slices.Contains(authTokens, token) {
+			return sendDeepLError(ctx, fiber.StatusForbidden, "Authorization failure, check auth_key")
+		}
+		return ctx.Next()
+	}
+}
+
+// addDeepLRoutes adds the endpoints of the DeepL API v2, so that the tools
+// speaking it can use the gateway as their DeepL server.
+func addDeepLRoutes(app *fiber.App, clientManager *client.ClientManager, config configs.DeepL, authTokens []string) {
+	var characterCount atomic.Int64
+	characterLimit := config.CharacterLimit
+	if characterLimit <= 0 {
+		characterLimit = deepLUnlimited
+	}
+
+	deepl := app.Group("/v2", deepLAuthMiddleware(authTokens))
+
+	deepl.Post("/translate", func(ctx *fiber.Ctx) error {
+		var request DeepLTranslateRequest
+		if err := ctx.BodyParser(&request); err != nil {
+			logger.Error("Invalid request", zap.Error(err))
+			return sendDeepLError(ctx, fiber.StatusBadRequest, "Invalid request")
+		}
+		// the form values point into the request buffer, which is reused once the
+		// handler returns while the texts end up in the translation memory
+		for i, text := range request.Text {
+			request.Text[i] = strings.Clone(text)
+		}
+		if len(request.Text) == 0 {
+			return sendDeepLError(ctx, fiber.StatusBadRequest, "Parameter 'text' not specified.")
+		}
+		if len(request.Text) > deepLMaxTexts {
+			return sendDeepLError(ctx, fiber.StatusRequestEntityTooLarge, "Too many texts, the maximum is "+strconv.Itoa(deepLMaxTexts)+".")
+		}
+		target, ok := findDeepLLanguage(deepLTargetLanguages, request.TargetLang)
+		if !ok {
+			// the deprecated EN, PT and ZH target languages
+			target, ok = findDeepLLanguage(deepLSourceLanguages, request.TargetLang)
+		}
+		if !ok {
+			return sendDeepLError(ctx, fiber.StatusBadRequest, "Value for 'target_lang' not supported.")
+		}
+		source := deepLLanguage{Language: "auto"}
+		if request.SourceLang != "" {
+			if source, ok = findDeepLLanguage(deepLSourceLanguages, request.SourceLang); !ok {
+				return sendDeepLError(ctx, fiber.StatusBadRequest, "Value for 'source_lang' not supported.")
+			}
+		}
+		if !slices.Contains(deepLFormalities, request.Formality) {
+			return sendDeepLError(ctx, fiber.StatusBadRequest, "Value for 'formality' not supported.")
+		}
+		if !slices.Contains(deepLTagHandlings, request.TagHandling) {
+			return sendDeepLError(ctx, fiber.StatusBadRequest, "Value for 'tag_handling' not supported.")
+		}
+
+		characters := 0
+		for _, text := range request.Text {
+			characters += utf8.RuneCountInString(text)
+		}
+		if characterCount.Load()+int64(characters) > characterLimit {
+			return sendDeepLError(ctx, 456, "Quota exceeded. The character limit has been reached.")
+		}
+
+		translateCtx := client.WithTranslateOptions(ctx.Context(), client.TranslateOptions{
+			Formality:   request.Formality,
+			TagHandling: request.TagHandling,
+		})
+		translations, err := translateAll(request.Text, func(text string) (DeepLTranslation, error) {
+			return translateDeepL(translateCtx, clientManager, config.Model, text, source, target)
+		})
+		if err != nil {
+			logger.Error("Error translating text", zap.String("TargetLang", target.Code), zap.Error(err))
+			return sendDeepLTranslationError(ctx, err)
+		}
+
+		characterCount.Add(int64(characters))
+		return ctx.Status(fiber.StatusOK).JSON(DeepLTranslateResponse{Translations: translations})
+	})
+
+	usage := func(ctx *fiber.Ctx) error {
+		return ctx.Status(fiber.StatusOK).JSON(DeepLUsageResponse{
+			CharacterCount: characterCount.Load(),
+			CharacterLimit: characterLimit,
+		})
+	}
+	deepl.Get("/usage", usage)
+	deepl.Post("/usage", usage)
+
+	languages := func(ctx *fiber.Ctx) error {
+		languageType := ctx.FormValue("type", ctx.Query("type", "source"))
+		if languageType != "source" && languageType != "target" {
+			return sendDeepLError(ctx, fiber.StatusBadRequest, "Value for 'type' not supported.")
+		}
+		list := deepLSourceLanguages
+		if languageType == "target" {
+			list = deepLTargetLanguages
+		}
+		response := make([]DeepLLanguageResponse, 0, len(list))
+		for _, language := range list {
+			item := DeepLLanguageResponse{Language: language.Code, Name: language.Name}
+			if languageType == "target" {
+				item.SupportsFormality = &language.SupportsFormality
+			}
+			response = append(response, item)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(response)
+	}
+	deepl.Get("/languages", languages)
+	deepl.Post("/languages", languages)
+}
+
+// translateDeepL translates a text with the configured model, or with the
+// model chosen by the routing rules.
+func translateDeepL(ctx context.Context, clientManager *client.ClientManager, model string, text string, source deepLLanguage, target deepLLanguage) (DeepLTranslation, error) {
+	var modelClient client.Client
+	var err error
+	if model != "" {
+		modelClient, err = clientManager.GetClientByName(model)
+	} else {
+		modelClient, err = clientManager.Route(text, source.Language, target.Language)
+		if err != nil {
+			return DeepLTranslation{}, errNoDeepLModel
+		}
+	}
+	if err != nil {
+		return DeepLTranslation{}, err
+	}
+
+	translatedText, _, err := clientManager.Complete(ctx, modelClient, text, source.Language, target.Language, false)
+	if err != nil {
+		return DeepLTranslation{}, err
+	}
+	detected := source.Code
+	if detected == "" {
+		if code, _ := client.DetectLanguage(text); code != "" {
+			detected = deepLLanguageCode(code)
+		}
+	}
+	return DeepLTranslation{DetectedSourceLanguage: detected, Text: translatedText}, nil
+}
+
+// sendDeepLTranslationError answers a failed translation with the status
+// codes of the DeepL API.
+func sendDeepLTranslationError(ctx *fiber.Ctx, err error) error {
+	var circuitOpen *client.CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(circuitOpen.RetryAfter.Seconds()))))
+		return sendDeepLError(ctx, fiber.StatusServiceUnavailable, "Service temporarily unavailable, try again later.")
+	}
+	if errors.Is(err, errNoDeepLModel) {
+		return sendDeepLError(ctx, fiber.StatusBadRequest, "No model configured for this language pair, set deepl.model or add a routing rule.")
+	}
+	if refused, ok := client.AsRefusal(err); ok {
+		return sendDeepLError(ctx, fiber.StatusBadRequest, "Translation refused: "+refused.Refusal.Reason)
+	}
+	return sendDeepLError(ctx, fiber.StatusInternalServerError, "Internal server error")
+}
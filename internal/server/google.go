@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/internal/configs"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+// googleMaxTexts is the number of texts Google accepts in a single request.
+const googleMaxTexts = 128
+
+// googleReliableConfidence is the confidence from which a detection is
+// reported as reliable.
+const googleReliableConfidence = 0.9
+
+// errNoGoogleModel is returned when no model is configured for the Google API
+// and no routing rule matches.
+var errNoGoogleModel = errors.New("no model for the language pair")
+
+// googleTexts is the q parameter of the JSON requests, a text or a list of
+// texts.
+type googleTexts []string
+
+func (t *googleTexts) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = googleTexts{text}
+		return nil
+	}
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return err
+	}
+	*t = texts
+	return nil
+}
+
+// GoogleTranslateRequest is the request of the Google Cloud Translation API
+// v2, sent as JSON, form-encoded or query parameters with repeated q.
+type GoogleTranslateRequest struct {
+	Q      googleTexts `json:"q"`
+	Source string      `json:"source"`
+	Target string      `json:"target"`
+	Format string      `json:"format"` // html (default) or text
+	Model  string      `json:"model"`  // a model of the gateway, defaults to the configured one
+}
+
+type GoogleTranslation struct {
+	TranslatedText         string `json:"translatedText"`
+	DetectedSourceLanguage string `json:"detectedSourceLanguage,omitempty"`
+	Model                  string `json:"model,omitempty"`
+}
+
+type GoogleDetection struct {
+	Language   string  `json:"language"`
+	IsReliable bool    `json:"isReliable"`
+	Confidence float64 `json:"confidence"`
+}
+
+type GoogleLanguage struct {
+	Language string `json:"language"`
+	Name     string `json:"name,omitempty"`
+}
+
+// googleResponse wraps the responses of the Google API in their data field.
+func googleResponse(data any) fiber.Map {
+	return fiber.Map{"data": data}
+}
+
+// sendGoogleError answers with the error body of the Google APIs.
+func sendGoogleError(ctx *fiber.Ctx, code int, status string, message string) error {
+	return ctx.Status(code).JSON(fiber.Map{"error": fiber.Map{
+		"code":    code,
+		"message": message,
+		"errors":  []fiber.Map{{"message": message, "domain": "global", "reason": "invalid"}},
+		"status":  status,
+	}})
+}
+
+func sendGoogleBadRequest(ctx *fiber.Ctx, message string) error {
+	return sendGoogleError(ctx, fiber.StatusBadRequest, "INVALID_ARGUMENT", message)
+}
+
+// googleAuthMiddleware accepts the auth tokens sent as the key parameter, the
+// X-Goog-Api-Key header or a Bearer authorization.
+func googleAuthMiddleware(authTokens []string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token := ctx.Query("key", ctx.Get("X-Goog-Api-Key"))
+		if token == "" {
+			token = strings.TrimPrefix(ctx.Get(fiber.HeaderAuthorization), "Bearer ")
+		}
+		if token == "" || !slices.Contains(authTokens, token) {
+			return sendGoogleError(ctx, fiber.StatusForbidden, "PERMISSION_DENIED", "The request is missing a valid API key.")
+		}
+		return ctx.Next()
+	}
+}
+
+// parseGoogleRequest reads a request from its JSON body, or from its form and
+// query parameters.
+func parseGoogleRequest(ctx *fiber.Ctx) (GoogleTranslateRequest, error) {
+	var request GoogleTranslateRequest
+	if strings.HasPrefix(ctx.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		if err := json.Unmarshal(ctx.Body(), &request); err != nil {
+			return request, err
+		}
+	}
+	if len(request.Q) == 0 {
+		for _, q := range ctx.Context().QueryArgs().PeekMulti("q") {
+			request.Q = append(request.Q, string(q))
+		}
+		for _, q := range ctx.Context().PostArgs().PeekMulti("q") {
+			request.Q = append(request.Q, string(q))
+		}
+	}
+	for field, value := range map[*string]string{
+		&request.Source: "source",
+		&request.Target: "target",
+		&request.Format: "format",
+		&request.Model:  "model",
+	} {
+		if *field == "" {
+			// the form values point into the request buffer, which is reused once
+			// the handler returns while the languages end up in the translation
+			// memory
+			*field = strings.Clone(ctx.FormValue(value, ctx.Query(value)))
+		}
+	}
+	return request, nil
+}
+
+// addGoogleRoutes adds the endpoints of the Google Cloud Translation API v2,
+// so that the apps hard-wired to Google Translate can use the gateway.
+func addGoogleRoutes(app *fiber.App, clientManager *client.ClientManager, config configs.Google, authTokens []string) {
+	google := app.Group("/language/translate/v2", googleAuthMiddleware(authTokens))
+
+	translate := func(ctx *fiber.Ctx) error {
+		request, err := parseGoogleRequest(ctx)
+		if err != nil {
+			logger.Error("Invalid request", zap.Error(err))
+			return sendGoogleBadRequest(ctx, "Invalid JSON payload received.")
+		}
+		if len(request.Q) == 0 {
+			return sendGoogleBadRequest(ctx, "Required Text")
+		}
+		if len(request.Q) > googleMaxTexts {
+			return sendGoogleBadRequest(ctx, "Too many text segments")
+		}
+		if request.Target == "" {
+			return sendGoogleBadRequest(ctx, "Required Target")
+		}
+		if request.Format != "" && request.Format != "html" && request.Format != "text" {
+			return sendGoogleBadRequest(ctx, "Invalid Value")
+		}
+
+		model := config.Model
+		if request.Model != "" && request.Model != "nmt" && request.Model != "base" {
+			if _, err := clientManager.GetClientByName(request.Model); err != nil {
+				return sendGoogleBadRequest(ctx, "Invalid Value")
+			}
+			model = request.Model
+		}
+
+		translations, err := translateAll(request.Q, func(text string) (GoogleTranslation, error) {
+			return translateGoogle(ctx.Context(), clientManager, model, text, request)
+		})
+		if err != nil {
+			logger.Error("Error translating text", zap.String("Target", request.Target), zap.Error(err))
+			return sendGoogleTranslationError(ctx, err)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(googleResponse(fiber.Map{"translations": translations}))
+	}
+	google.Get("/", translate)
+	google.Post("/", translate)
+
+	detect := func(ctx *fiber.Ctx) error {
+		request, err := parseGoogleRequest(ctx)
+		if err != nil {
+			return sendGoogleBadRequest(ctx, "Invalid JSON payload received.")
+		}
+		if len(request.Q) == 0 {
+			return sendGoogleBadRequest(ctx, "Required Text")
+		}
+		detections := make([][]GoogleDetection, 0, len(request.Q))
+		for _, text := range request.Q {
+			code, confidence := client.DetectLanguage(text)
+			if code == "" {
+				code = "und"
+			}
+			detections = append(detections, []GoogleDetection{{
+				Language:   code,
+				IsReliable: confidence >= googleReliableConfidence,
+				Confidence: math.Round(confidence*100) / 100,
+			}})
+		}
+		return ctx.Status(fiber.StatusOK).JSON(googleResponse(fiber.Map{"detections": detections}))
+	}
+	google.Get("/detect", detect)
+	google.Post("/detect", detect)
+
+	// the names are only returned in English, when a target is given
+	languages := func(ctx *fiber.Ctx) error {
+		request, err := parseGoogleRequest(ctx)
+		if err != nil {
+			return sendGoogleBadRequest(ctx, "Invalid JSON payload received.")
+		}
+		codes := client.LanguageCodes()
+		languages := make([]GoogleLanguage, 0, len(codes))
+		for _, code := range codes {
+			language := GoogleLanguage{Language: code}
+			if request.Target != "" {
+				language.Name = client.LanguageName(code)
+			}
+			languages = append(languages, language)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(googleResponse(fiber.Map{"languages": languages}))
+	}
+	google.Get("/languages", languages)
+	google.Post("/languages", languages)
+}
+
+// translateGoogle translates a text with the given model, or with the model
+// chosen by the routing rules. The HTML texts keep their markup.
+func translateGoogle(ctx context.Context, clientManager *client.ClientManager, model string, text string, request GoogleTranslateRequest) (GoogleTranslation, error) {
+	source, target := "auto", client.LanguageName(request.Target)
+	if request.Source != "" {
+		source = client.LanguageName(request.Source)
+	}
+
+	var modelClient client.Client
+	var err error
+	if model != "" {
+		modelClient, err = clientManager.GetClientByName(model)
+	} else {
+		modelClient, err = clientManager.Route(text, source, target)
+		if err != nil {
+			return GoogleTranslation{}, errNoGoogleModel
+		}
+	}
+	if err != nil {
+		return GoogleTranslation{}, err
+	}
+
+	// the format defaults to html, but only the texts with markup are
+	// translated as such
+	if request.Format != "text" && strings.Contains(text, "<") {
+		ctx = client.WithTranslateOptions(ctx, client.TranslateOptions{TagHandling: "html"})
+	}
+	translatedText, _, err := clientManager.Complete(ctx, modelClient, text, source, target, false)
+	if err != nil {
+		return GoogleTranslation{}, err
+	}
+
+	translation := GoogleTranslation{TranslatedText: translatedText, Model: request.Model}
+	if request.Source == "" {
+		if code, _ := client.DetectLanguage(text); code != "" {
+			translation.DetectedSourceLanguage = code
+		} else {
+			translation.DetectedSourceLanguage = "und"
+		}
+	}
+	return translation, nil
+}
+
+// sendGoogleTranslationError answers a failed translation with the status
+// codes of the Google APIs.
+func sendGoogleTranslationError(ctx *fiber.Ctx, err error) error {
+	var circuitOpen *client.CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(circuitOpen.RetryAfter.Seconds()))))
+		return sendGoogleError(ctx, fiber.StatusServiceUnavailable, "UNAVAILABLE", "The service is currently unavailable.")
+	}
+	if errors.Is(err, errNoGoogleModel) {
+		return sendGoogleBadRequest(ctx, "No model configured for this language pair, set google.model or add a routing rule.")
+	}
+	if refused, ok := client.AsRefusal(err); ok {
+		return sendGoogleBadRequest(ctx, "Translation refused: "+refused.Refusal.Reason)
+	}
+	return sendGoogleError(ctx, fiber.StatusInternalServerError, "INTERNAL", "Internal error encountered.")
+}
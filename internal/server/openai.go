@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+// chatStreamBufferSize is the size of the chunks relayed from a streamed chat
+// completion.
+const chatStreamBufferSize = 4096
+
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// sendOpenAIError answers with the error body of the OpenAI API.
+func sendOpenAIError(ctx *fiber.Ctx, status int, errorType string, code string, message string) error {
+	return ctx.Status(status).JSON(fiber.Map{"error": fiber.Map{
+		"message": message,
+		"type":    errorType,
+		"param":   nil,
+		"code":    code,
+	}})
+}
+
+// chatModels returns the sorted names of the models whose upstream speaks the
+// chat completions API.
+func chatModels(clientManager *client.ClientManager) []string {
+	var names []string
+	for _, name := range clientManager.GetAllNames() {
+		modelClient, err := clientManager.GetClientByName(name)
+		if err != nil {
+			continue
+		}
+		if _, ok := client.AsChatProxy(modelClient); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// addOpenAIRoutes adds an OpenAI compatible chat completions proxy, the model
+// of a request being the name of a model of the gateway. The requests go
+// through the rate limiter and the circuit breaker of the model, with its
+// credentials.
+func addOpenAIRoutes(app *fiber.App, clientManager *client.ClientManager, authMiddleware fiber.Handler) {
+	openai := app.Group("/v1", authMiddleware)
+
+	openai.Get("/models", func(ctx *fiber.Ctx) error {
+		models := make([]OpenAIModel, 0)
+		for _, name := range chatModels(clientManager) {
+			models = append(models, OpenAIModel{ID: name, Object: "model", OwnedBy: "polyglot-gate"})
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"object": "list", "data": models})
+	})
+
+	openai.Get("/models/:model", func(ctx *fiber.Ctx) error {
+		name := ctx.Params("model")
+		if !slices.Contains(chatModels(clientManager), name) {
+			return sendOpenAIError(ctx, fiber.StatusNotFound, "invalid_request_error", "model_not_found", "The model `"+name+"` does not exist")
+		}
+		return ctx.Status(fiber.StatusOK).JSON(OpenAIModel{ID: name, Object: "model", OwnedBy: "polyglot-gate"})
+	})
+
+	openai.Post("/chat/completions", func(ctx *fiber.Ctx) error {
+		var request map[string]json.RawMessage
+		if err := json.Unmarshal(ctx.Body(), &request); err != nil {
+			return sendOpenAIError(ctx, fiber.StatusBadRequest, "invalid_request_error", "invalid_json", "Invalid JSON body")
+		}
+		var name string
+		if err := json.Unmarshal(request["model"], &name); err != nil || name == "" {
+			return sendOpenAIError(ctx, fiber.StatusBadRequest, "invalid_request_error", "missing_model", "You must provide a model parameter")
+		}
+		var stream bool
+		if value, ok := request["stream"]; ok {
+			_ = json.Unmarshal(value, &stream)
+		}
+
+		modelClient, err := clientManager.GetClientByName(name)
+		if err != nil {
+			return sendOpenAIError(ctx, fiber.StatusNotFound, "invalid_request_error", "model_not_found", "The model `"+name+"` does not exist")
+		}
+		proxy, ok := client.AsChatProxy(modelClient)
+		if !ok {
+			return sendOpenAIError(ctx, fiber.StatusBadRequest, "invalid_request_error", "model_not_supported", "The model `"+name+"` does not support chat completions")
+		}
+
+		// the response of a streamed completion is written after the handler
+		// returns, so its upstream request must outlive the handler
+		proxyCtx, cancel := context.WithCancel(context.Background())
+		start := time.Now()
+		resp, err := proxy.ProxyChat(proxyCtx, request)
+		if err != nil {
+			cancel()
+			logger.Error("Chat completion failed", zap.String("Model", name), zap.Error(err))
+			var circuitOpen *client.CircuitOpenError
+			if errors.As(err, &circuitOpen) {
+				ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(circuitOpen.RetryAfter.Seconds()))))
+				return sendOpenAIError(ctx, fiber.StatusServiceUnavailable, "server_error", "model_unavailable", "The model `"+name+"` is temporarily unavailable")
+			}
+			return sendOpenAIError(ctx, fiber.StatusBadGateway, "server_error", "upstream_error", "The upstream of the model `"+name+"` failed")
+		}
+		logger.Info("Chat completion proxied",
+			zap.String("Model", name),
+			zap.Bool("Stream", stream),
+			zap.Int("Status", resp.StatusCode),
+			zap.Duration("Latency", time.Since(start)),
+		)
+
+		ctx.Status(resp.StatusCode)
+		ctx.Set(fiber.HeaderContentType, resp.Header.Get(fiber.HeaderContentType))
+		if !strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), "text/event-stream") {
+			defer cancel()
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				logger.Error("Failed to read chat completion", zap.String("Model", name), zap.Error(err))
+				return sendOpenAIError(ctx, fiber.StatusBadGateway, "server_error", "upstream_error", "The upstream of the model `"+name+"` failed")
+			}
+			return ctx.Send(body)
+		}
+
+		ctx.Set(fiber.HeaderCacheControl, "no-cache")
+		ctx.Set(fiber.HeaderConnection, "keep-alive")
+		ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			defer resp.Body.Close()
+			buffer := make([]byte, chatStreamBufferSize)
+			for {
+				n, err := resp.Body.Read(buffer)
+				if n > 0 {
+					if _, writeErr := w.Write(buffer[:n]); writeErr != nil {
+						return
+					}
+					// the caller went away, stop the upstream generation
+					if flushErr := w.Flush(); flushErr != nil {
+						logger.Debug("Chat stream closed by caller", zap.String("Model", name))
+						return
+					}
+				}
+				if err != nil {
+					if err != io.EOF {
+						logger.Error("Chat stream failed", zap.String("Model", name), zap.Error(err))
+					}
+					return
+				}
+			}
+		})
+		return nil
+	})
+}
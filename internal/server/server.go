@@ -2,17 +2,23 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	fiberLogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/nerdneilsfield/Polyglot-Gate-Server/internal/configs"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
 	loggerPkg "github.com/nerdneilsfield/shlogin/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -70,6 +76,14 @@ type TranslationResponse struct {
 	TranslatedText string `json:"translated_text"`
 }
 
+// RefusalResponse answers a translation refused by the model or rejected by
+// its upstream.
+type RefusalResponse struct {
+	Error   string         `json:"error"`
+	Refusal client.Refusal `json:"refusal"`
+	Cached  bool           `json:"cached"`
+}
+
 type HcfyRequest struct {
 	Name        string   `json:"name"`
 	Text        string   `json:"text"`
@@ -115,7 +129,54 @@ func NewAuthMiddleware(authTokens []string) func() fiber.Handler {
 	}
 }
 
-func CreateServer(config *configs.Config) *fiber.App {
+// sendTranslationError answers a failed translation, telling the caller when
+// to retry if the model is currently unavailable, or why it was refused.
+func sendTranslationError(ctx *fiber.Ctx, err error) error {
+	var circuitOpen *client.CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(circuitOpen.RetryAfter.Seconds()))))
+	}
+	status, body := translationError(err)
+	return ctx.Status(status).JSON(body)
+}
+
+// translationError returns the status and the body answering a failed
+// translation.
+func translationError(err error) (int, any) {
+	var circuitOpen *client.CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		return fiber.StatusServiceUnavailable, fiber.Map{"error": "Model unavailable"}
+	}
+	// a refusal fails the same way when retried, the body tells the caller why
+	// so that it can fall back instead
+	if refused, ok := client.AsRefusal(err); ok {
+		return fiber.StatusUnprocessableEntity, RefusalResponse{
+			Error:   "Translation refused",
+			Refusal: refused.Refusal,
+			Cached:  refused.Cached,
+		}
+	}
+	return fiber.StatusInternalServerError, fiber.Map{"error": "Error translating text"}
+}
+
+// translateAll translates the texts of a batch request concurrently, it
+// returns the errors of all the failed texts.
+func translateAll[T any](texts []string, translate func(text string) (T, error)) ([]T, error) {
+	results := make([]T, len(texts))
+	errs := make([]error, len(texts))
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = translate(text)
+		}()
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
+
+func CreateServer(config *configs.Config) (*fiber.App, error) {
 	logger.Debug("Creating server", zap.Any("config", config))
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(ctx *fiber.Ctx, err error) error {
@@ -145,7 +206,39 @@ func CreateServer(config *configs.Config) *fiber.App {
 
 	api := app.Group("/api/v1", authMiddleware())
 
-	clientManager := configs.CreateClientManager(config.Models)
+	cache, err := configs.CreateCache(config.Cache)
+	if err != nil {
+		return nil, err
+	}
+	clientManager, err := configs.CreateClientManager(config.Models, cache)
+	if err != nil {
+		return nil, err
+	}
+	clientManager.SetRoutes(configs.CreateRoutes(config.Routes))
+
+	tm, tmOptions, err := configs.CreateTranslationMemory(config.TranslationMemory)
+	if err != nil {
+		return nil, err
+	}
+	if tm != nil {
+		clientManager.SetTranslationMemory(tm, tmOptions)
+		addTranslationMemoryRoutes(api, tm, tmOptions)
+	}
+
+	addDeepLRoutes(app, clientManager, config.DeepL, config.AuthToken)
+	addGoogleRoutes(app, clientManager, config.Google, config.AuthToken)
+	addOpenAIRoutes(app, clientManager, authMiddleware())
+	addStreamRoutes(api, clientManager)
+	addCaptionsRoutes(app, clientManager, config.AuthToken)
+
+	// the admin routes are not reachable with the tokens of the public API
+	if config.AdminToken != "" {
+		admin := app.Group("/api/admin", NewAuthMiddleware([]string{config.AdminToken})())
+		addCacheAdminRoutes(admin, clientManager)
+		if tm != nil {
+			addTranslationMemoryAdminRoutes(admin, tm)
+		}
+	}
 
 	// translate api
 	api.Post("/translate", func(ctx *fiber.Ctx) error {
@@ -169,13 +262,39 @@ func CreateServer(config *configs.Config) *fiber.App {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Client not found"})
 		}
 
-		translatedText, err := client.Complete(ctx.Context(), request.Text, request.From, request.To, request.ForceRefresh)
+		translatedText, usedClient, err := clientManager.Complete(ctx.Context(), client, request.Text, request.From, request.To, request.ForceRefresh)
 		if err != nil {
 			logger.Error("Error translating text", zap.String("ModelName", request.ModelName), zap.Error(err))
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error translating text"})
+			return sendTranslationError(ctx, err)
 		}
 
-		return ctx.Status(fiber.StatusOK).JSON(TranslationResponse{ModelName: request.ModelName, TranslatedText: translatedText})
+		return ctx.Status(fiber.StatusOK).JSON(TranslationResponse{ModelName: usedClient.GetClientInfo().Name, TranslatedText: translatedText})
+	})
+
+	// translate api choosing the model by routing rules
+	api.Post("/translate/auto", func(ctx *fiber.Ctx) error {
+		var request TranslationRequest
+		if err := ctx.BodyParser(&request); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		if request.From == "" || request.To == "" || request.Text == "" {
+			logger.Error("Invalid request", zap.Any("request", request))
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		client, err := clientManager.Route(request.Text, request.From, request.To)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No route found"})
+		}
+
+		translatedText, usedClient, err := clientManager.Complete(ctx.Context(), client, request.Text, request.From, request.To, request.ForceRefresh)
+		if err != nil {
+			logger.Error("Error translating text", zap.String("ModelName", client.GetClientInfo().Name), zap.Error(err))
+			return sendTranslationError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(TranslationResponse{ModelName: usedClient.GetClientInfo().Name, TranslatedText: translatedText})
 	})
 
 	modelGroup := api.Group("/models")
@@ -184,6 +303,7 @@ func CreateServer(config *configs.Config) *fiber.App {
 		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 			"models_by_name":     clientManager.GetAllNames(),
 			"models_by_endpoint": clientManager.GetAllEndpoints(),
+			"model_states":       clientManager.GetAllBreakerStates(),
 		})
 	})
 
@@ -208,13 +328,13 @@ func CreateServer(config *configs.Config) *fiber.App {
 				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Client not found"})
 			}
 
-			translatedText, err := client.Complete(ctx.Context(), request.Text, request.From, request.To, request.ForceRefresh)
+			translatedText, usedClient, err := clientManager.Complete(ctx.Context(), client, request.Text, request.From, request.To, request.ForceRefresh)
 			if err != nil {
 				logger.Error("Error translating text", zap.String("endpoint", endpoint), zap.Error(err))
-				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error translating text"})
+				return sendTranslationError(ctx, err)
 			}
 			return ctx.Status(fiber.StatusOK).JSON(
-				TranslationResponse{ModelName: client.GetClientInfo().ModelName, TranslatedText: translatedText},
+				TranslationResponse{ModelName: usedClient.GetClientInfo().ModelName, TranslatedText: translatedText},
 			)
 		})
 	}
@@ -236,16 +356,20 @@ func CreateServer(config *configs.Config) *fiber.App {
 			request.Source = "auto"
 		}
 
-		client, err := clientManager.GetClientByName(request.Name)
+		modelClient, err := clientManager.GetClientByName(request.Name)
 		if err != nil {
 			logger.Error("Client not found", zap.String("name", request.Name), zap.Error(err))
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Client not found"})
 		}
 
-		translatedText, err := client.Complete(ctx.Context(), request.Text, request.Source, request.Destination[0], false)
+		translateCtx := context.Context(ctx.Context())
+		if config.Hcfy.Hedge {
+			translateCtx = client.WithHedging(translateCtx)
+		}
+		translatedText, _, err := clientManager.Complete(translateCtx, modelClient, request.Text, request.Source, request.Destination[0], false)
 		if err != nil {
 			logger.Error("Error translating text", zap.String("name", request.Name), zap.Error(err))
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error translating text"})
+			return sendTranslationError(ctx, err)
 		}
 		splitText := strings.Split(translatedText, "\n")
 		return ctx.Status(fiber.StatusOK).JSON(
@@ -283,10 +407,10 @@ func CreateServer(config *configs.Config) *fiber.App {
 			sourceLang := deeplToLang[request.SourceLang]
 			targetLang := deeplToLang[request.TragetLang]
 
-			translatedText, err := client.Complete(ctx.Context(), request.Text, sourceLang, targetLang, false)
+			translatedText, _, err := clientManager.Complete(ctx.Context(), client, request.Text, sourceLang, targetLang, false)
 			if err != nil {
 				logger.Error("Error translating text", zap.String("endpoint", endpoint), zap.Error(err))
-				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error translating text"})
+				return sendTranslationError(ctx, err)
 			}
 
 			return ctx.Status(fiber.StatusOK).JSON(DeepLXResponse{
@@ -300,11 +424,15 @@ func CreateServer(config *configs.Config) *fiber.App {
 		})
 	}
 
-	return app
+	return app, nil
 }
 
 func RunServer(config *configs.Config) error {
-	app := CreateServer(config)
+	app, err := CreateServer(config)
+	if err != nil {
+		logger.Error("Failed to create server", zap.Error(err))
+		return err
+	}
 	logger.Info("Starting server", zap.String("host", config.Host), zap.Int("port", config.Port))
 	return app.Listen(fmt.Sprintf("%s:%d", config.Host, config.Port))
 }
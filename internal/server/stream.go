@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+// TranslationDelta is the data of a delta event of a streamed translation.
+type TranslationDelta struct {
+	Text string `json:"text"`
+}
+
+// writeEvent writes a server-sent event and flushes it to the caller.
+func writeEvent(w *bufio.Writer, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// addStreamRoutes adds the translation streamed over server-sent events. The
+// translation is sent as delta events while the model generates it, followed
+// by a done event with the whole translation or an error event. Invalid
+// requests are answered before the stream starts.
+func addStreamRoutes(api fiber.Router, clientManager *client.ClientManager) {
+	api.Post("/translate/stream", func(ctx *fiber.Ctx) error {
+		var request TranslationRequestWithModelName
+		if err := ctx.BodyParser(&request); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		if request.ModelName == "" || request.From == "" || request.To == "" || request.Text == "" {
+			logger.Error("Invalid request", zap.Any("request", request))
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		modelClient, err := clientManager.GetClientByName(request.ModelName)
+		if err != nil {
+			logger.Error("Client not found",
+				zap.String("ModelName", request.ModelName),
+				zap.Error(err),
+				zap.Any("clients", clientManager.GetAllNames()),
+			)
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Client not found"})
+		}
+
+		ctx.Set(fiber.HeaderContentType, "text/event-stream")
+		ctx.Set(fiber.HeaderCacheControl, "no-cache")
+		ctx.Set(fiber.HeaderConnection, "keep-alive")
+		// the stream outlives the handler, the translation is cancelled when the
+		// caller goes away
+		ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			streamCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			start := time.Now()
+			translatedText, usedClient, err := clientManager.CompleteStream(streamCtx, modelClient, request.Text, request.From, request.To, request.ForceRefresh, func(delta string) error {
+				if err := writeEvent(w, "delta", TranslationDelta{Text: delta}); err != nil {
+					cancel()
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				if streamCtx.Err() != nil {
+					logger.Debug("Translation stream closed by caller", zap.String("ModelName", request.ModelName))
+					return
+				}
+				logger.Error("Error translating text", zap.String("ModelName", request.ModelName), zap.Error(err))
+				_, body := translationError(err)
+				_ = writeEvent(w, "error", body)
+				return
+			}
+
+			logger.Debug("Translation streamed",
+				zap.String("ModelName", usedClient.GetClientInfo().Name),
+				zap.Duration("Latency", time.Since(start)),
+			)
+			_ = writeEvent(w, "done", TranslationResponse{ModelName: usedClient.GetClientInfo().Name, TranslatedText: translatedText})
+		})
+		return nil
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nerdneilsfield/Polyglot-Gate-Server/pkg/client"
+	"go.uber.org/zap"
+)
+
+// addTranslationMemoryRoutes adds the search of the translation memory.
+func addTranslationMemoryRoutes(api fiber.Router, tm *client.TranslationMemory, options client.TMOptions) {
+	api.Get("/tm/search", func(ctx *fiber.Ctx) error {
+		text, from, to := ctx.Query("text"), ctx.Query("from"), ctx.Query("to")
+		if text == "" || to == "" {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+		limit := ctx.QueryInt("limit", 10)
+		threshold := ctx.QueryFloat("threshold", options.Threshold)
+		if limit <= 0 || threshold < 0 || threshold > 1 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		matches := tm.Search(text, from, to, limit, threshold)
+		if matches == nil {
+			matches = []client.TMMatch{}
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"matches": matches})
+	})
+}
+
+// addTranslationMemoryAdminRoutes adds the import of TMX documents and the
+// export of the memory.
+func addTranslationMemoryAdminRoutes(admin fiber.Router, tm *client.TranslationMemory) {
+	admin.Get("/tm/export", func(ctx *fiber.Ctx) error {
+		format := ctx.Query("format", "tmx")
+		var body bytes.Buffer
+		writer, err := client.NewTranslationWriter(format, &body)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		for _, segment := range tm.Segments() {
+			if err = writer.Write(client.CachedTranslation{CacheRecord: client.CacheRecord{
+				From:        segment.From,
+				To:          segment.To,
+				Source:      segment.Source,
+				Translation: segment.Target,
+			}}); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			logger.Error("Failed to export translation memory", zap.Error(err))
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export translation memory"})
+		}
+		ctx.Set(fiber.HeaderContentType, exportContentTypes[format])
+		return ctx.Status(fiber.StatusOK).Send(body.Bytes())
+	})
+
+	admin.Post("/tm/import", func(ctx *fiber.Ctx) error {
+		imported, err := tm.ImportTMX(bytes.NewReader(ctx.Body()))
+		if err != nil {
+			logger.Error("Failed to import translation memory", zap.Error(err))
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid TMX"})
+		}
+		logger.Info("Translation memory imported", zap.Int("Segments", imported))
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"imported": imported, "segments": tm.Len()})
+	})
+}
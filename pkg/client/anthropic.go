@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const anthropicVersion = "2023-06-01"
+
+type AnthropicClient struct {
+	BaseClient
+	apiKey string
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float32            `json:"temperature"`
+}
+
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+}
+
+func NewAnthropicClient(info ClientInfo, apiKey string) *AnthropicClient {
+	return &AnthropicClient{
+		BaseClient: *NewBaseClient(info),
+		apiKey:     apiKey,
+	}
+}
+
+func (c *AnthropicClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call Anthropic Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.createMessage)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *AnthropicClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.createMessage), onDelta)
+}
+
+func (c *AnthropicClient) createMessage(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	request := anthropicRequest{
+		Model:  c.info.ModelName,
+		System: c.info.SystemPrompt,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: c.userPrompt(ctx, inputText, fromLanguage, toLanguage)},
+		},
+		MaxTokens:   c.info.MaxTokens,
+		Temperature: c.info.Temperature,
+	}
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var resp anthropicResponse
+	url := strings.TrimSuffix(c.info.BaseURL, "/") + "/messages"
+	if err := doJSON(ctx, http.MethodPost, url, headers, request, &resp); err != nil {
+		logger.Error("Anthropic Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	var content strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	switch resp.StopReason {
+	case "refusal":
+		logger.Error("Content blocked by model",
+			zap.String("Content", content.String()),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+		)
+		return "", &ContentBlockedError{ModelName: c.info.ModelName, Reason: resp.StopReason}
+	case "max_tokens":
+		logger.Error("Response truncated by max tokens",
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.Int("MaxTokens", c.info.MaxTokens),
+		)
+		return "", fmt.Errorf("response truncated by max tokens from model %s", c.info.ModelName)
+	}
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
+	}
+
+	return cleanContent(content.String()), nil
+}
+
+func (c *AnthropicClient) GetClientInfo() ClientInfo {
+	return c.info
+}
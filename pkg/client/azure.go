@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAzureAPIVersion = "2024-06-01"
+	// adTokenCheckInterval is how often the Azure AD token file is checked
+	// for a rotated token.
+	adTokenCheckInterval = 10 * time.Second
+)
+
+type AzureOptions struct {
+	APIKey      string
+	Deployment  string
+	APIVersion  string
+	ADTokenFile string // Azure AD token file, re-read when its content changes
+}
+
+// AzureOpenAIClient talks to an Azure OpenAI deployment. It reuses the OpenAI
+// chat completion flow with go-openai's Azure config.
+type AzureOpenAIClient struct {
+	OpenAIClient
+	options        AzureOptions
+	tokenMu        sync.Mutex
+	token          string
+	tokenModTime   time.Time // modification time of the token file when it was read
+	tokenCheckedAt time.Time
+}
+
+func NewAzureOpenAIClient(info ClientInfo, options AzureOptions) (*AzureOpenAIClient, error) {
+	if options.APIVersion == "" {
+		options.APIVersion = defaultAzureAPIVersion
+	}
+	if options.Deployment == "" {
+		options.Deployment = info.ModelName
+	}
+
+	c := &AzureOpenAIClient{
+		OpenAIClient: OpenAIClient{
+			BaseClient: *NewBaseClient(info),
+			apiKey:     options.APIKey,
+		},
+		options: options,
+	}
+
+	if options.ADTokenFile == "" {
+		c.setOpenAIClient(openai.NewClientWithConfig(c.azureConfig(options.APIKey, openai.APITypeAzure)))
+		return c, nil
+	}
+	if err := c.refreshADToken(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *AzureOpenAIClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call Azure OpenAI Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Deployment", c.options.Deployment),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	if c.options.ADTokenFile != "" {
+		if err := c.refreshADToken(); err != nil {
+			logger.Error("Failed to refresh Azure AD token", zap.Error(err), zap.String("Name", c.info.Name))
+			return "", err
+		}
+	}
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.createChatCompletion)
+}
+
+func (c *AzureOpenAIClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	logger.Debug("Call Azure OpenAI CompleteStream",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Deployment", c.options.Deployment),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	if c.options.ADTokenFile != "" {
+		if err := c.refreshADToken(); err != nil {
+			logger.Error("Failed to refresh Azure AD token", zap.Error(err), zap.String("Name", c.info.Name))
+			return "", err
+		}
+	}
+
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.createChatCompletionStream, onDelta)
+}
+
+func (c *AzureOpenAIClient) azureConfig(token string, apiType openai.APIType) openai.ClientConfig {
+	config := openai.DefaultAzureConfig(token, c.info.BaseURL)
+	config.APIType = apiType
+	config.APIVersion = c.options.APIVersion
+	config.HTTPClient = defaultHTTPClient
+	config.AzureModelMapperFunc = func(model string) string {
+		return c.options.Deployment
+	}
+	return config
+}
+
+// refreshADToken re-reads the Azure AD token file when it has been modified
+// and rebuilds the underlying client when the token has been rotated. The file
+// is checked at most every adTokenCheckInterval.
+func (c *AzureOpenAIClient) refreshADToken() error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token != "" && time.Since(c.tokenCheckedAt) < adTokenCheckInterval {
+		return nil
+	}
+
+	info, err := os.Stat(c.options.ADTokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read Azure AD token file %s: %w", c.options.ADTokenFile, err)
+	}
+	c.tokenCheckedAt = time.Now()
+	if c.token != "" && info.ModTime().Equal(c.tokenModTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.options.ADTokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read Azure AD token file %s: %w", c.options.ADTokenFile, err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return fmt.Errorf("empty Azure AD token file: %s", c.options.ADTokenFile)
+	}
+	c.tokenModTime = info.ModTime()
+	if token == c.token {
+		return nil
+	}
+	logger.Debug("Azure AD token loaded", zap.String("Name", c.info.Name), zap.String("Path", c.options.ADTokenFile))
+	c.token = token
+	c.setOpenAIClient(openai.NewClientWithConfig(c.azureConfig(token, openai.APITypeAzureAD)))
+	return nil
+}
+
+func (c *AzureOpenAIClient) GetClientInfo() ClientInfo {
+	return c.info
+}
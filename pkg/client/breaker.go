@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// breakerWindowSize is the number of recent results the error rate is computed over.
+const breakerWindowSize = 20
+
+type BreakerOptions struct {
+	MaxFailures  int           // consecutive failures that open the breaker, 0 uses the default
+	ErrorRate    float64       // error rate over the recent requests that opens the breaker, 0 uses the default
+	OpenDuration time.Duration // how long the breaker stays open before probing, 0 uses the default
+}
+
+// CircuitBreaker fails fast while an upstream is down. After OpenDuration it
+// lets a single probe request through and closes again if the probe succeeds.
+type CircuitBreaker struct {
+	options  BreakerOptions
+	state    string
+	failures int
+	results  []bool // ring buffer of the recent results, true for failures
+	next     int
+	openedAt time.Time
+	probing  bool
+	mu       sync.Mutex
+}
+
+func NewCircuitBreaker(options BreakerOptions) *CircuitBreaker {
+	if options.MaxFailures <= 0 {
+		options.MaxFailures = 5
+	}
+	if options.ErrorRate <= 0 {
+		options.ErrorRate = 0.5
+	}
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = 30 * time.Second
+	}
+	return &CircuitBreaker{options: options, state: BreakerClosed}
+}
+
+// Allow reports whether a request may be sent upstream, and otherwise how long
+// the breaker stays open. Allowed requests must report their outcome with Record.
+func (b *CircuitBreaker) Allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if wait := time.Until(b.openedAt.Add(b.options.OpenDuration)); wait > 0 {
+			return wait, false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return 0, true
+	case BreakerHalfOpen:
+		// only one probe at a time
+		if b.probing {
+			return b.options.OpenDuration, false
+		}
+		b.probing = true
+		return 0, true
+	}
+	return 0, true
+}
+
+// Record reports the outcome of a request allowed by Allow.
+func (b *CircuitBreaker) Record(err error) {
+	failed := isUpstreamFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// the request says nothing about the upstream
+	if isCallerError(err) {
+		b.probing = false
+		return
+	}
+
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.reset()
+		}
+		return
+	}
+
+	if len(b.results) < breakerWindowSize {
+		b.results = append(b.results, failed)
+	} else {
+		b.results[b.next] = failed
+		b.next = (b.next + 1) % breakerWindowSize
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.options.MaxFailures || b.errorRate() >= b.options.ErrorRate {
+		b.open()
+	}
+}
+
+// State returns closed, open or half_open.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.options.OpenDuration {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// errorRate is only meaningful once the window has been filled up.
+func (b *CircuitBreaker) errorRate() float64 {
+	if len(b.results) < breakerWindowSize {
+		return 0
+	}
+	failures := 0
+	for _, failed := range b.results {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(b.results))
+}
+
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
+
+func (b *CircuitBreaker) reset() {
+	b.state = BreakerClosed
+	b.failures = 0
+	b.results = b.results[:0]
+	b.next = 0
+}
+
+// errLimiterWait wraps the errors of a request that never left the rate limiter.
+var errLimiterWait = errors.New("rate limiter wait failed")
+
+// isCallerError reports whether err was caused by the caller giving up rather
+// than by the upstream.
+func isCallerError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, errLimiterWait) || errors.Is(err, errDeltaRejected)
+}
+
+// isUpstreamFailure reports whether err says something about the health of the
+// upstream, as opposed to the content, an invalid request or the caller giving
+// up.
+func isUpstreamFailure(err error) bool {
+	return err != nil && !IsRefusal(err) && !isCallerError(err)
+}
@@ -1,7 +1,9 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -13,8 +15,79 @@ type Cache interface {
 	Set(key string, value string, expiration time.Duration) error
 }
 
+// CacheAdmin is implemented by the caches that can be inspected and purged.
+type CacheAdmin interface {
+	Cache
+	Stats() (CacheStats, error)
+	// Lookup returns an entry without counting a hit or a miss.
+	Lookup(key string) (CacheEntry, bool, error)
+	// Range calls fn for every entry until it returns false.
+	Range(fn func(entry CacheEntry) bool) error
+	Delete(keys ...string) error
+}
+
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+type CacheEntry struct {
+	Key       string
+	Value     string
+	ExpiresAt time.Time // zero when the entry never expires
+}
+
+// CacheRecord is the value stored by the clients for a translation, it keeps
+// what is needed to export the cache.
+type CacheRecord struct {
+	Model       string    `json:"model"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Source      string    `json:"source"`
+	Translation string    `json:"translation"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Refusal is set on the negative entries, which cache a refusal instead
+	// of a translation.
+	Refusal *Refusal `json:"refusal,omitempty"`
+}
+
+func (r CacheRecord) encode() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// decodeCacheRecord decodes a cached value, values stored as plain text are
+// returned as the translation.
+func decodeCacheRecord(value string) CacheRecord {
+	var record CacheRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil || (record.Translation == "" && record.Refusal == nil) {
+		return CacheRecord{Translation: value}
+	}
+	return record
+}
+
+// cacheCounters counts the hits and misses of a cache.
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func (c *cacheCounters) hit() {
+	c.hits.Add(1)
+}
+
+func (c *cacheCounters) miss() {
+	c.misses.Add(1)
+}
+
+func (c *cacheCounters) stats(entries int) CacheStats {
+	return CacheStats{Entries: entries, Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
 type MemoryCache struct {
 	cache *cache.Cache
+	cacheCounters
 }
 
 func NewMemoryCache(defaultExpiration, cleanupInterval time.Duration) *MemoryCache {
@@ -25,8 +98,10 @@ func NewMemoryCache(defaultExpiration, cleanupInterval time.Duration) *MemoryCac
 
 func (mc *MemoryCache) Get(key string) (string, error) {
 	if value, found := mc.cache.Get(key); found {
+		mc.hit()
 		return value.(string), nil
 	}
+	mc.miss()
 	logger.Warn("Cache miss", zap.String("key", key))
 	return "", fmt.Errorf("cache miss")
 }
@@ -36,3 +111,35 @@ func (mc *MemoryCache) Set(key string, value string, expiration time.Duration) e
 	mc.cache.Set(key, value, expiration)
 	return nil
 }
+
+func (mc *MemoryCache) Stats() (CacheStats, error) {
+	return mc.stats(mc.cache.ItemCount()), nil
+}
+
+func (mc *MemoryCache) Lookup(key string) (CacheEntry, bool, error) {
+	value, expiresAt, found := mc.cache.GetWithExpiration(key)
+	if !found {
+		return CacheEntry{}, false, nil
+	}
+	return CacheEntry{Key: key, Value: value.(string), ExpiresAt: expiresAt}, true, nil
+}
+
+func (mc *MemoryCache) Range(fn func(entry CacheEntry) bool) error {
+	for key, item := range mc.cache.Items() {
+		entry := CacheEntry{Key: key, Value: item.Object.(string)}
+		if item.Expiration > 0 {
+			entry.ExpiresAt = time.Unix(0, item.Expiration)
+		}
+		if !fn(entry) {
+			break
+		}
+	}
+	return nil
+}
+
+func (mc *MemoryCache) Delete(keys ...string) error {
+	for _, key := range keys {
+		mc.cache.Delete(key)
+	}
+	return nil
+}
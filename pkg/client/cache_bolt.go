@@ -0,0 +1,239 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var boltBucket = []byte("translations")
+
+type BoltCacheOptions struct {
+	Path            string
+	MaxEntries      int           // entries kept after compaction, 0 means no limit
+	CompactInterval time.Duration // how often expired and extra entries are removed
+}
+
+// BoltCache is a Cache persisted on disk with bbolt, so that translations
+// survive restarts. Every value is stored with its expiration time.
+type BoltCache struct {
+	db      *bolt.DB
+	options BoltCacheOptions
+	done    chan struct{}
+	once    sync.Once
+	cacheCounters
+}
+
+func NewBoltCache(options BoltCacheOptions) (*BoltCache, error) {
+	if options.CompactInterval <= 0 {
+		options.CompactInterval = time.Hour
+	}
+
+	db, err := bolt.Open(options.Path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache %s: %w", options.Path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create cache bucket: %w", err)
+	}
+
+	bc := &BoltCache{db: db, options: options, done: make(chan struct{})}
+	go bc.compactLoop()
+	return bc, nil
+}
+
+func (bc *BoltCache) Get(key string) (string, error) {
+	entry, found, err := bc.Lookup(key)
+	if err != nil {
+		logger.Error("Failed to read cache", zap.Error(err), zap.String("key", key))
+		return "", err
+	}
+	if !found {
+		bc.miss()
+		logger.Debug("Cache miss", zap.String("key", key))
+		return "", fmt.Errorf("cache miss")
+	}
+	bc.hit()
+	return entry.Value, nil
+}
+
+func (bc *BoltCache) Set(key string, value string, expiration time.Duration) error {
+	logger.Debug("Setting cache", zap.String("key", key), zap.Duration("expiration", expiration))
+	var expiresAt time.Time
+	if expiration > 0 {
+		expiresAt = time.Now().Add(expiration)
+	}
+	return bc.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), encodeBoltValue(expiresAt, value))
+	})
+}
+
+func (bc *BoltCache) Stats() (CacheStats, error) {
+	var entries int
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		entries = tx.Bucket(boltBucket).Stats().KeyN
+		return nil
+	})
+	return bc.stats(entries), err
+}
+
+func (bc *BoltCache) Lookup(key string) (CacheEntry, bool, error) {
+	var entry CacheEntry
+	var found bool
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		expiresAt, value, err := decodeBoltValue(data)
+		if err != nil {
+			return err
+		}
+		if !expiresAt.IsZero() && time.Now().After(expiresAt) {
+			return nil
+		}
+		entry, found = CacheEntry{Key: key, Value: value, ExpiresAt: expiresAt}, true
+		return nil
+	})
+	return entry, found, err
+}
+
+func (bc *BoltCache) Range(fn func(entry CacheEntry) bool) error {
+	now := time.Now()
+	return bc.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			expiresAt, value, err := decodeBoltValue(data)
+			if err != nil || (!expiresAt.IsZero() && now.After(expiresAt)) {
+				continue
+			}
+			if !fn(CacheEntry{Key: string(key), Value: value, ExpiresAt: expiresAt}) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (bc *BoltCache) Delete(keys ...string) error {
+	return bc.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, key := range keys {
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close stops the compaction and closes the database.
+func (bc *BoltCache) Close() error {
+	bc.once.Do(func() { close(bc.done) })
+	return bc.db.Close()
+}
+
+func (bc *BoltCache) compactLoop() {
+	ticker := time.NewTicker(bc.options.CompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-bc.done:
+			return
+		case <-ticker.C:
+			if err := bc.Compact(); err != nil {
+				logger.Error("Failed to compact cache", zap.Error(err), zap.String("Path", bc.options.Path))
+			}
+		}
+	}
+}
+
+// Compact removes the expired entries, then the entries expiring first until
+// at most MaxEntries are left.
+func (bc *BoltCache) Compact() error {
+	type entry struct {
+		key       []byte
+		expiresAt time.Time
+	}
+
+	removed := 0
+	err := bc.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		now := time.Now()
+		var expired [][]byte
+		var entries []entry
+		err := bucket.ForEach(func(key, data []byte) error {
+			expiresAt, _, err := decodeBoltValue(data)
+			if err != nil || (!expiresAt.IsZero() && now.After(expiresAt)) {
+				expired = append(expired, slices.Clone(key))
+				return nil
+			}
+			entries = append(entries, entry{key: slices.Clone(key), expiresAt: expiresAt})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if bc.options.MaxEntries > 0 && len(entries) > bc.options.MaxEntries {
+			// entries without expiration are evicted last
+			slices.SortFunc(entries, func(a, b entry) int {
+				switch {
+				case a.expiresAt.IsZero() && b.expiresAt.IsZero():
+					return 0
+				case a.expiresAt.IsZero():
+					return 1
+				case b.expiresAt.IsZero():
+					return -1
+				}
+				return a.expiresAt.Compare(b.expiresAt)
+			})
+			for _, e := range entries[:len(entries)-bc.options.MaxEntries] {
+				expired = append(expired, e.key)
+			}
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	if err == nil && removed > 0 {
+		logger.Info("Cache compacted", zap.String("Path", bc.options.Path), zap.Int("Removed", removed))
+	}
+	return err
+}
+
+// encodeBoltValue prefixes the value with its expiration as unix nanoseconds,
+// 0 meaning that the value never expires.
+func encodeBoltValue(expiresAt time.Time, value string) []byte {
+	data := make([]byte, 8+len(value))
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(data, uint64(expiresAt.UnixNano()))
+	}
+	copy(data[8:], value)
+	return data
+}
+
+func decodeBoltValue(data []byte) (time.Time, string, error) {
+	if len(data) < 8 {
+		return time.Time{}, "", fmt.Errorf("corrupted cache entry")
+	}
+	var expiresAt time.Time
+	if nanos := binary.BigEndian.Uint64(data); nanos != 0 {
+		expiresAt = time.Unix(0, int64(nanos))
+	}
+	return expiresAt, string(data[8:]), nil
+}
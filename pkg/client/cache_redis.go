@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	redisTimeout = 500 * time.Millisecond
+	// redisRetryInterval is how long Redis is skipped after a failure.
+	redisRetryInterval = 10 * time.Second
+)
+
+// errRedisDown is returned when the keys could only be deleted from the memory
+// fallback.
+var errRedisDown = errors.New("redis unreachable, only the memory cache was purged")
+
+type RedisCacheOptions struct {
+	URL    string // redis://[user:password@]host:port/db
+	Prefix string // prepended to every key, so that several gateways can share a database
+}
+
+// RedisCache is a Cache shared by the gateway replicas. While Redis is
+// unreachable, the translations are cached in memory instead.
+type RedisCache struct {
+	client    *redis.Client
+	prefix    string
+	fallback  *MemoryCache
+	downUntil time.Time
+	mu        sync.Mutex
+	cacheCounters
+}
+
+func NewRedisCache(options RedisCacheOptions) (*RedisCache, error) {
+	redisOptions, err := redis.ParseURL(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	redisOptions.DialTimeout = redisTimeout
+	redisOptions.ReadTimeout = redisTimeout
+	redisOptions.WriteTimeout = redisTimeout
+
+	rc := &RedisCache{
+		client:   redis.NewClient(redisOptions),
+		prefix:   options.Prefix,
+		fallback: NewMemoryCache(time.Hour, time.Minute*10),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := rc.client.Ping(ctx).Err(); err != nil {
+		// the gateway still starts, Redis is retried later
+		rc.markDown(err)
+	}
+	return rc, nil
+}
+
+func (rc *RedisCache) Get(key string) (string, error) {
+	if !rc.available() {
+		return rc.fallback.Get(key)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	value, err := rc.client.Get(ctx, rc.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		rc.miss()
+		logger.Debug("Cache miss", zap.String("key", key))
+		return "", fmt.Errorf("cache miss")
+	}
+	if err != nil {
+		rc.markDown(err)
+		return rc.fallback.Get(key)
+	}
+	rc.hit()
+	return value, nil
+}
+
+func (rc *RedisCache) Set(key string, value string, expiration time.Duration) error {
+	if !rc.available() {
+		return rc.fallback.Set(key, value, expiration)
+	}
+
+	logger.Debug("Setting cache", zap.String("key", key), zap.Duration("expiration", expiration))
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	// an expiration of 0 keeps the key forever
+	if err := rc.client.Set(ctx, rc.prefix+key, value, max(expiration, 0)).Err(); err != nil {
+		rc.markDown(err)
+		return rc.fallback.Set(key, value, expiration)
+	}
+	return nil
+}
+
+// Stats counts the keys with the prefix and the entries of the memory
+// fallback, and the hits and misses of this gateway including the ones served
+// by the memory fallback.
+func (rc *RedisCache) Stats() (CacheStats, error) {
+	fallback, _ := rc.fallback.Stats()
+	entries := fallback.Entries
+	if rc.available() {
+		if err := rc.scan(func(keys []string) error {
+			entries += len(keys)
+			return nil
+		}); err != nil {
+			rc.markDown(err)
+		}
+	}
+	stats := rc.stats(entries)
+	stats.Hits += fallback.Hits
+	stats.Misses += fallback.Misses
+	return stats, nil
+}
+
+// Lookup looks the key up in Redis, then in the memory fallback where the
+// translations are cached while Redis is down.
+func (rc *RedisCache) Lookup(key string) (CacheEntry, bool, error) {
+	if !rc.available() {
+		return rc.fallback.Lookup(key)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	pipe := rc.client.Pipeline()
+	get := pipe.Get(ctx, rc.prefix+key)
+	ttl := pipe.PTTL(ctx, rc.prefix+key)
+	if _, err := pipe.Exec(ctx); errors.Is(err, redis.Nil) {
+		return rc.fallback.Lookup(key)
+	} else if err != nil {
+		rc.markDown(err)
+		return rc.fallback.Lookup(key)
+	}
+	return redisEntry(key, get.Val(), ttl.Val()), true, nil
+}
+
+// Range calls fn for the entries of Redis, then for the entries of the memory
+// fallback that Redis does not have.
+func (rc *RedisCache) Range(fn func(entry CacheEntry) bool) error {
+	errStop := errors.New("stop")
+	seen := make(map[string]bool)
+	if rc.available() {
+		err := rc.scan(func(keys []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+			defer cancel()
+			pipe := rc.client.Pipeline()
+			gets := make([]*redis.StringCmd, len(keys))
+			ttls := make([]*redis.DurationCmd, len(keys))
+			for i, key := range keys {
+				gets[i] = pipe.Get(ctx, key)
+				ttls[i] = pipe.PTTL(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			for i, key := range keys {
+				// the key expired since the scan
+				if gets[i].Err() != nil {
+					continue
+				}
+				key = strings.TrimPrefix(key, rc.prefix)
+				seen[key] = true
+				if !fn(redisEntry(key, gets[i].Val(), ttls[i].Val())) {
+					return errStop
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errStop) {
+			return nil
+		}
+		if err != nil {
+			rc.markDown(err)
+		}
+	}
+
+	return rc.fallback.Range(func(entry CacheEntry) bool {
+		return seen[entry.Key] || fn(entry)
+	})
+}
+
+// Delete deletes the keys from Redis and from the memory fallback. While
+// Redis is down only the memory fallback is purged and an error is returned.
+func (rc *RedisCache) Delete(keys ...string) error {
+	// deleting the local copies too, in case they were set while Redis was down
+	rc.fallback.Delete(keys...)
+	if len(keys) == 0 {
+		return nil
+	}
+	if !rc.available() {
+		return errRedisDown
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, rc.prefix+key)
+	}
+	if err := rc.client.Del(ctx, prefixed...).Err(); err != nil {
+		rc.markDown(err)
+		return fmt.Errorf("%w: %w", errRedisDown, err)
+	}
+	return nil
+}
+
+// scan calls fn with the batches of keys having the prefix.
+func (rc *RedisCache) scan(fn func(keys []string) error) error {
+	pattern := redisGlobEscaper.Replace(rc.prefix) + "*"
+	var cursor uint64
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		keys, next, err := rc.client.Scan(ctx, cursor, pattern, 1000).Result()
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// redisEntry builds an entry from a value and its PTTL, which is negative
+// when the key has no expiration.
+func redisEntry(key string, value string, ttl time.Duration) CacheEntry {
+	entry := CacheEntry{Key: key, Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	return entry
+}
+
+// Close closes the connections to Redis.
+func (rc *RedisCache) Close() error {
+	return rc.client.Close()
+}
+
+func (rc *RedisCache) available() bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return time.Now().After(rc.downUntil)
+}
+
+func (rc *RedisCache) markDown(err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if time.Now().Before(rc.downUntil) {
+		return
+	}
+	rc.downUntil = time.Now().Add(redisRetryInterval)
+	logger.Warn("Redis unreachable, falling back to memory cache",
+		zap.Error(err),
+		zap.Duration("RetryIn", redisRetryInterval),
+	)
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisCache(t *testing.T, prefix string) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rc, err := NewRedisCache(RedisCacheOptions{URL: "redis://" + mr.Addr(), Prefix: prefix})
+	if err != nil {
+		t.Fatalf("NewRedisCache: %v", err)
+	}
+	t.Cleanup(func() { rc.Close() })
+	return rc, mr
+}
+
+func TestRedisCachePrefix(t *testing.T) {
+	rc, mr := newTestRedisCache(t, "pgs:")
+
+	if err := rc.Set("key", "value", time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := mr.Get("pgs:key"); err != nil || got != "value" {
+		t.Fatalf("redis pgs:key = %q, %v, want value", got, err)
+	}
+	if mr.Exists("key") {
+		t.Fatal("key stored without the prefix")
+	}
+	if got, err := rc.Get("key"); err != nil || got != "value" {
+		t.Fatalf("Get = %q, %v, want value", got, err)
+	}
+
+	// keys of another gateway sharing the database are left alone
+	mr.Set("other:key", "other")
+	var keys []string
+	if err := rc.Range(func(entry CacheEntry) bool {
+		keys = append(keys, entry.Key)
+		return true
+	}); err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "key" {
+		t.Fatalf("Range keys = %v, want [key]", keys)
+	}
+	if err := rc.Delete("key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if mr.Exists("pgs:key") || !mr.Exists("other:key") {
+		t.Fatal("Delete removed the wrong keys")
+	}
+}
+
+func TestRedisCacheModelTTL(t *testing.T) {
+	rc, mr := newTestRedisCache(t, "pgs:")
+
+	c := NewBaseClient(ClientInfo{Name: "model", RateLimit: 100, CacheExpireHours: 2})
+	c.SetCache(rc)
+	calls := 0
+	call := func(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+		calls++
+		return "translated " + inputText, nil
+	}
+
+	for range 2 {
+		if got, err := c.complete(context.Background(), "hello", "en", "zh", false, call); err != nil || got != "translated hello" {
+			t.Fatalf("complete = %q, %v", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("upstream calls = %d, want 1", calls)
+	}
+
+	key := "pgs:" + cacheKey(c.info, "hello", "en", "zh", TranslateOptions{})
+	if ttl := mr.TTL(key); ttl != 2*time.Hour {
+		t.Fatalf("TTL = %v, want 2h", ttl)
+	}
+
+	mr.FastForward(2*time.Hour + time.Second)
+	if mr.Exists(key) {
+		t.Fatal("translation still cached after its TTL")
+	}
+	if _, err := c.complete(context.Background(), "hello", "en", "zh", false, call); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("upstream calls = %d, want 2", calls)
+	}
+}
+
+func TestRedisCacheFallback(t *testing.T) {
+	rc, mr := newTestRedisCache(t, "pgs:")
+
+	if err := rc.Set("before", "redis", time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	mr.Close()
+
+	if err := rc.Set("during", "memory", time.Hour); err != nil {
+		t.Fatalf("Set while down: %v", err)
+	}
+	if rc.available() {
+		t.Fatal("Redis still marked available after a failure")
+	}
+	if got, err := rc.Get("during"); err != nil || got != "memory" {
+		t.Fatalf("Get while down = %q, %v, want memory", got, err)
+	}
+
+	// the admin calls are served by the memory fallback without waiting for
+	// Redis
+	start := time.Now()
+	entry, found, err := rc.Lookup("during")
+	if err != nil || !found || entry.Value != "memory" {
+		t.Fatalf("Lookup while down = %+v, %v, %v", entry, found, err)
+	}
+	var keys []string
+	if err := rc.Range(func(entry CacheEntry) bool {
+		keys = append(keys, entry.Key)
+		return true
+	}); err != nil {
+		t.Fatalf("Range while down: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "during" {
+		t.Fatalf("Range keys while down = %v, want [during]", keys)
+	}
+	if stats, err := rc.Stats(); err != nil || stats.Entries != 1 {
+		t.Fatalf("Stats while down = %+v, %v, want 1 entry", stats, err)
+	}
+	if elapsed := time.Since(start); elapsed >= redisTimeout {
+		t.Fatalf("admin calls took %v while Redis is down", elapsed)
+	}
+
+	if err := rc.Delete("during"); err == nil {
+		t.Fatal("Delete while down did not report that Redis was not purged")
+	}
+	if _, found, _ := rc.Lookup("during"); found {
+		t.Fatal("memory entry not deleted while Redis is down")
+	}
+}
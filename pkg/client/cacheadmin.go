@@ -0,0 +1,195 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CacheFilter selects the cached translations of a model and a language pair,
+// empty fields match everything.
+type CacheFilter struct {
+	Model string
+	From  string
+	To    string
+}
+
+func (f CacheFilter) matches(key string) bool {
+	name, from, to, ok := parseCacheKey(key)
+	if !ok {
+		// entries of an older key version are only matched by an empty filter
+		return f == CacheFilter{}
+	}
+	return (f.Model == "" || f.Model == name) && sameLanguage(f.From, from) && sameLanguage(f.To, to)
+}
+
+// sameLanguage compares a filter language with a cached one, by name or code.
+func sameLanguage(filter string, language string) bool {
+	return filter == "" || strings.EqualFold(filter, language) || LanguageCode(filter) == LanguageCode(language)
+}
+
+// parseCacheKey extracts the client name and the languages from a key built
+// by CacheKey.
+func parseCacheKey(key string) (string, string, string, bool) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 5 || parts[0] != cacheKeyVersion {
+		return "", "", "", false
+	}
+	fields := make([]string, 3)
+	for i, part := range parts[1:4] {
+		field, err := url.QueryUnescape(part)
+		if err != nil {
+			return "", "", "", false
+		}
+		fields[i] = field
+	}
+	return fields[0], fields[1], fields[2], true
+}
+
+// CachedTranslation is a cached translation with its expiration, as exported
+// and imported by the cache administration.
+type CachedTranslation struct {
+	CacheRecord
+	ExpiresAt time.Time `json:"expires_at"` // zero when the translation never expires
+}
+
+// caches returns the distinct caches of the clients, which are either one
+// per client or a single shared one.
+func (m *ClientManager) caches() []CacheAdmin {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var caches []CacheAdmin
+	for _, client := range m.clientsWithName {
+		holder, ok := client.(CacheHolder)
+		if !ok {
+			continue
+		}
+		cache, ok := holder.Cache().(CacheAdmin)
+		if ok && !slices.Contains(caches, cache) {
+			caches = append(caches, cache)
+		}
+	}
+	return caches
+}
+
+func (m *ClientManager) clientCache(name string) (ClientInfo, CacheAdmin, error) {
+	client, err := m.GetClientByName(name)
+	if err != nil {
+		return ClientInfo{}, nil, err
+	}
+	if holder, ok := client.(CacheHolder); ok {
+		if cache, ok := holder.Cache().(CacheAdmin); ok {
+			return client.GetClientInfo(), cache, nil
+		}
+	}
+	return ClientInfo{}, nil, fmt.Errorf("cache of %s cannot be administrated", name)
+}
+
+// CacheStats sums the statistics of the caches.
+func (m *ClientManager) CacheStats() (CacheStats, error) {
+	var total CacheStats
+	for _, cache := range m.caches() {
+		stats, err := cache.Stats()
+		if err != nil {
+			logger.Error("Failed to get cache stats", zap.Error(err))
+			return CacheStats{}, err
+		}
+		total.Entries += stats.Entries
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+	}
+	return total, nil
+}
+
+// PurgeCache deletes the cached translations matching the filter and returns
+// how many were deleted.
+func (m *ClientManager) PurgeCache(filter CacheFilter) (int, error) {
+	purged := 0
+	for _, cache := range m.caches() {
+		var keys []string
+		err := cache.Range(func(entry CacheEntry) bool {
+			if filter.matches(entry.Key) {
+				keys = append(keys, entry.Key)
+			}
+			return true
+		})
+		if err == nil {
+			err = cache.Delete(keys...)
+		}
+		if err != nil {
+			logger.Error("Failed to purge cache", zap.Error(err), zap.Any("Filter", filter))
+			return purged, err
+		}
+		purged += len(keys)
+	}
+	logger.Info("Cache purged", zap.Any("Filter", filter), zap.Int("Purged", purged))
+	return purged, nil
+}
+
+// LookupCache looks up the cached translation of a text without counting a
+// hit or a miss.
+func (m *ClientManager) LookupCache(name string, inputText string, fromLanguage string, toLanguage string) (CachedTranslation, bool, error) {
+	info, cache, err := m.clientCache(name)
+	if err != nil {
+		return CachedTranslation{}, false, err
+	}
+	entry, found, err := cache.Lookup(CacheKey(info, inputText, fromLanguage, toLanguage))
+	if err != nil || !found {
+		return CachedTranslation{}, false, err
+	}
+	return CachedTranslation{CacheRecord: decodeCacheRecord(entry.Value), ExpiresAt: entry.ExpiresAt}, true, nil
+}
+
+// ExportCache calls fn for every cached translation matching the filter.
+func (m *ClientManager) ExportCache(filter CacheFilter, fn func(translation CachedTranslation) error) error {
+	for _, cache := range m.caches() {
+		var fnErr error
+		err := cache.Range(func(entry CacheEntry) bool {
+			if _, _, _, ok := parseCacheKey(entry.Key); !ok || !filter.matches(entry.Key) {
+				return true
+			}
+			record := decodeCacheRecord(entry.Value)
+			if record.Refusal != nil {
+				return true
+			}
+			fnErr = fn(CachedTranslation{CacheRecord: record, ExpiresAt: entry.ExpiresAt})
+			return fnErr == nil
+		})
+		if err == nil {
+			err = fnErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportCache stores a translation in the cache of its model, under the key
+// derived from the current settings of the model. Translations without
+// expiration expire after the cache expire hours of the model.
+func (m *ClientManager) ImportCache(translation CachedTranslation) error {
+	if translation.Source == "" || translation.Translation == "" {
+		return fmt.Errorf("missing source or translation")
+	}
+	info, cache, err := m.clientCache(translation.Model)
+	if err != nil {
+		return err
+	}
+	expiration := time.Hour * time.Duration(info.CacheExpireHours)
+	if !translation.ExpiresAt.IsZero() {
+		expiration = time.Until(translation.ExpiresAt)
+		if expiration <= 0 {
+			return nil
+		}
+	}
+	if translation.CreatedAt.IsZero() {
+		translation.CreatedAt = time.Now()
+	}
+	key := CacheKey(info, translation.Source, translation.From, translation.To)
+	return cache.Set(key, translation.CacheRecord.encode(), expiration)
+}
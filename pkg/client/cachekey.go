@@ -0,0 +1,70 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// cacheKeyVersion is bumped whenever the key derivation changes, so that the
+// old entries of persistent caches are never read again.
+const cacheKeyVersion = "v2"
+
+// CacheKey derives the cache key of a translation. The client name and the
+// languages are kept readable, while the normalized text, the prompts and the
+// generation settings are hashed, so that changing any of them invalidates
+// the cached translations.
+func CacheKey(info ClientInfo, inputText string, fromLanguage string, toLanguage string) string {
+	return cacheKey(info, inputText, fromLanguage, toLanguage, TranslateOptions{})
+}
+
+// cacheKey also hashes the translate options, when there are any, since they
+// change the translation.
+func cacheKey(info ClientInfo, inputText string, fromLanguage string, toLanguage string, options TranslateOptions) string {
+	fields := []string{
+		info.ModelName,
+		info.Prompt,
+		info.SystemPrompt,
+		fmt.Sprintf("%g", info.Temperature),
+		fmt.Sprintf("%d", info.MaxTokens),
+		NormalizeText(inputText),
+	}
+	if options != (TranslateOptions{}) {
+		fields = append(fields, options.Formality, options.TagHandling)
+	}
+	digest := sha256.New()
+	for _, field := range fields {
+		// the length prefix keeps the fields from running into each other
+		fmt.Fprintf(digest, "%d:%s", len(field), field)
+	}
+	return CacheKeyPrefix(info.Name, fromLanguage, toLanguage) + hex.EncodeToString(digest.Sum(nil))
+}
+
+// CacheKeyPrefix is the prefix shared by the cache keys of a client and a
+// language pair. The languages are language codes, so that "English" and
+// "en" share the cached translations.
+func CacheKeyPrefix(name string, fromLanguage string, toLanguage string) string {
+	return strings.Join([]string{
+		cacheKeyVersion,
+		url.QueryEscape(name),
+		url.QueryEscape(LanguageCode(fromLanguage)),
+		url.QueryEscape(LanguageCode(toLanguage)),
+	}, ":") + ":"
+}
+
+// NormalizeText puts a text in Unicode NFC, unifies the line endings and
+// collapses the spaces within each line, so that texts differing only by
+// their encoding or spacing share a cache entry.
+func NormalizeText(text string) string {
+	text = norm.NFC.String(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
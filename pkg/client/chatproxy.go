@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// proxyHTTPClient has no timeout, the streamed chat completions last as long
+// as the caller keeps reading them.
+var proxyHTTPClient = &http.Client{Transport: http.DefaultTransport}
+
+// ChatProxy is implemented by the clients whose upstream speaks the OpenAI
+// chat completions API, so that the gateway can forward chat requests to them
+// with its own credentials.
+type ChatProxy interface {
+	// ProxyChat sends a chat completions request, its model replaced by the
+	// model of the client, and returns the upstream response as is, which is
+	// a stream of server-sent events for streaming requests. The caller closes
+	// the response body.
+	ProxyChat(ctx context.Context, request map[string]json.RawMessage) (*http.Response, error)
+}
+
+// AsChatProxy returns the chat proxy of a client, if chat requests can be
+// forwarded to it.
+func AsChatProxy(client Client) (ChatProxy, bool) {
+	proxy, ok := client.(ChatProxy)
+	if pool, isPool := client.(*PoolClient); isPool {
+		return proxy, pool.supportsChat()
+	}
+	return proxy, ok
+}
+
+// proxyChat sends a chat request through the circuit breaker and the rate
+// limiter of the client.
+func (c *BaseClient) proxyChat(ctx context.Context, url string, headers map[string]string, request map[string]json.RawMessage) (*http.Response, error) {
+	retryAfter, allowed := c.breaker.Allow()
+	if !allowed {
+		return nil, &CircuitOpenError{Name: c.info.Name, RetryAfter: retryAfter}
+	}
+	if err := c.limiter.Wait(ctx); err != nil {
+		c.breaker.Record(fmt.Errorf("%w: %w", errLimiterWait, err))
+		return nil, err
+	}
+
+	if c.info.ModelName != "" {
+		model, _ := json.Marshal(c.info.ModelName)
+		request["model"] = model
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		c.breaker.Record(nil)
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		c.breaker.Record(nil)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := proxyHTTPClient.Do(req)
+	if err != nil {
+		c.breaker.Record(err)
+		logger.Error("Chat proxy failed", zap.Error(err), zap.String("Name", c.info.Name), zap.String("Model", c.info.ModelName))
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		c.breaker.Record(&HTTPError{StatusCode: resp.StatusCode})
+	} else {
+		c.breaker.Record(nil)
+	}
+	return resp, nil
+}
+
+func (c *OpenAIClient) ProxyChat(ctx context.Context, request map[string]json.RawMessage) (*http.Response, error) {
+	url := strings.TrimSuffix(c.info.BaseURL, "/") + "/chat/completions"
+	return c.proxyChat(ctx, url, map[string]string{"Authorization": "Bearer " + c.apiKey}, request)
+}
+
+func (c *AzureOpenAIClient) ProxyChat(ctx context.Context, request map[string]json.RawMessage) (*http.Response, error) {
+	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		strings.TrimSuffix(c.info.BaseURL, "/"), c.options.Deployment, c.options.APIVersion)
+	headers := map[string]string{"api-key": c.apiKey}
+	if c.options.ADTokenFile != "" {
+		if err := c.refreshADToken(); err != nil {
+			logger.Error("Failed to refresh Azure AD token", zap.Error(err), zap.String("Name", c.info.Name))
+			return nil, err
+		}
+		c.tokenMu.Lock()
+		headers = map[string]string{"Authorization": "Bearer " + c.token}
+		c.tokenMu.Unlock()
+	}
+	return c.proxyChat(ctx, url, headers, request)
+}
+
+// ProxyChat uses the OpenAI compatible API of Ollama.
+func (c *OllamaClient) ProxyChat(ctx context.Context, request map[string]json.RawMessage) (*http.Response, error) {
+	return c.proxyChat(ctx, c.url("/v1/chat/completions"), nil, request)
+}
+
+// ProxyChat uses the OpenAI compatible API of the llama.cpp server.
+func (c *LlamaCppClient) ProxyChat(ctx context.Context, request map[string]json.RawMessage) (*http.Response, error) {
+	return c.proxyChat(ctx, c.url("/v1/chat/completions"), nil, request)
+}
+
+// ProxyChat forwards the request to the next member, a member answering with a
+// server error or failing is ejected as for the translations. The member is
+// released when the response body is closed, so that the streamed completions
+// count as outstanding while they are relayed.
+func (c *PoolClient) ProxyChat(ctx context.Context, request map[string]json.RawMessage) (*http.Response, error) {
+	member := c.acquire(map[*PoolMember]bool{})
+	proxy, ok := member.Client.(ChatProxy)
+	if !ok {
+		c.release(member, nil)
+		return nil, fmt.Errorf("pool %s does not support chat completions", c.info.Name)
+	}
+	resp, err := proxy.ProxyChat(ctx, request)
+	if err != nil {
+		c.release(member, err)
+		return nil, err
+	}
+
+	var failure error
+	if resp.StatusCode >= 500 {
+		failure = &HTTPError{StatusCode: resp.StatusCode}
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { c.release(member, failure) }}
+	return resp, nil
+}
+
+// supportsChat reports whether every member speaks the chat completions API.
+func (c *PoolClient) supportsChat() bool {
+	for _, member := range c.members {
+		if _, ok := member.Client.(ChatProxy); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// releasingBody calls release once when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -3,60 +3,273 @@ package client
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	loggerPkg "github.com/nerdneilsfield/shlogin/pkg/logger"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
 )
 
 var logger = loggerPkg.GetLogger()
 
+// defaultNegativeCacheTTL is how long refusals are cached by default.
+const defaultNegativeCacheTTL = 5 * time.Minute
+
 type Client interface {
 	Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error)
+	// CompleteStream translates like Complete and passes the pieces of the
+	// translation to onDelta as they arrive, it returns the whole translation.
+	// Clients that cannot stream pass the whole translation at once.
+	CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error)
 	GetClientInfo() ClientInfo
 }
 
+// HealthChecker is implemented by clients that can verify at startup that
+// their upstream is reachable and serving the configured model.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
 type ClientInfo struct {
 	Name             string
 	MaxTokens        int
 	Temperature      float32
 	RateLimit        float64
 	Prompt           string
+	SystemPrompt     string
 	ModelName        string
 	BaseURL          string
 	Endpoint         string
 	CacheExpireHours int
+	Timeout          time.Duration // per request timeout, 0 means no timeout
+	NegativeCacheTTL time.Duration // how long refusals are cached, 0 means defaultNegativeCacheTTL, negative disables
+	Breaker          BreakerOptions
+	Retry            RetryOptions
+}
+
+// BreakerStater is implemented by clients that track the health of their
+// upstream with a circuit breaker.
+type BreakerStater interface {
+	BreakerState() string
 }
 
 type BaseClient struct {
 	info    ClientInfo
 	limiter *rate.Limiter
 	cache   Cache
+	breaker *CircuitBreaker
+	flight  *singleflight.Group // coalesces the identical in-flight requests
+
+	throttleMu *sync.Mutex // serializes the changes of the limiter burst by throttle
 }
 
 func (c *BaseClient) GetClientInfo() ClientInfo {
 	return c.info
 }
 
+// CacheHolder is implemented by every client embedding BaseClient, it lets a
+// pool share its cache with the members, the clients share a persistent cache
+// and the caches be administrated.
+type CacheHolder interface {
+	Cache() Cache
+	SetCache(cache Cache)
+}
+
+func (c *BaseClient) Cache() Cache {
+	return c.cache
+}
+
+func (c *BaseClient) SetCache(cache Cache) {
+	c.cache = cache
+}
+
+func (c *BaseClient) BreakerState() string {
+	return c.breaker.State()
+}
+
 func (c *BaseClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
 	return "", nil
 }
 
+// completeFunc performs the actual upstream call for a single translation.
+type completeFunc func(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error)
+
+// complete wraps an upstream call with the cache lookup and the rate limiter
+// shared by every client type.
+func (c *BaseClient) complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, call completeFunc) (string, error) {
+	cacheKey := cacheKey(c.info, inputText, fromLanguage, toLanguage, translateOptions(ctx))
+
+	if !forceRefresh {
+		if cached, err := c.cache.Get(cacheKey); err == nil {
+			logger.Debug("Cache hit", zap.String("Key", cacheKey))
+			record := decodeCacheRecord(cached)
+			if record.Refusal != nil {
+				return "", &RefusedError{Refusal: *record.Refusal, Cached: true, Err: record.Refusal.err()}
+			}
+			return record.Translation, nil
+		}
+	}
+
+	return c.coalesce(ctx, cacheKey, func(ctx context.Context) (string, error) {
+		return c.callAndCache(ctx, cacheKey, inputText, fromLanguage, toLanguage, call)
+	})
+}
+
+// callAndCache calls the upstream through the circuit breaker and caches the
+// translation.
+func (c *BaseClient) callAndCache(ctx context.Context, cacheKey string, inputText string, fromLanguage string, toLanguage string, call completeFunc) (string, error) {
+	retryAfter, allowed := c.breaker.Allow()
+	if !allowed {
+		logger.Warn("Circuit breaker open",
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.Duration("RetryAfter", retryAfter),
+		)
+		return "", &CircuitOpenError{Name: c.info.Name, RetryAfter: retryAfter}
+	}
+
+	content, err := c.callWithRetry(ctx, inputText, fromLanguage, toLanguage, call)
+	c.breaker.Record(err)
+	record := CacheRecord{
+		Model:     c.info.Name,
+		From:      fromLanguage,
+		To:        toLanguage,
+		Source:    inputText,
+		CreatedAt: time.Now(),
+	}
+	if err != nil {
+		refusal, ok := refusalOf(err, c.info.Name)
+		if !ok {
+			return "", err
+		}
+		// the same input would be refused again, answer it from the cache for
+		// a while instead of calling the upstream
+		if ttl := c.negativeCacheTTL(); ttl > 0 {
+			record.Refusal = &refusal
+			c.setCache(cacheKey, record, ttl)
+		}
+		return "", &RefusedError{Refusal: refusal, Err: err}
+	}
+
+	record.Translation = content
+	c.setCache(cacheKey, record, time.Hour*time.Duration(c.info.CacheExpireHours))
+	return content, nil
+}
+
+func (c *BaseClient) setCache(cacheKey string, record CacheRecord, expiration time.Duration) {
+	if err := c.cache.Set(cacheKey, record.encode(), expiration); err != nil {
+		logger.Warn("Failed to set cache", zap.Error(err), zap.String("Key", cacheKey))
+	}
+}
+
+func (c *BaseClient) negativeCacheTTL() time.Duration {
+	if c.info.NegativeCacheTTL == 0 {
+		return defaultNegativeCacheTTL
+	}
+	return c.info.NegativeCacheTTL
+}
+
+// callWithRetry calls the upstream, retrying throttled, failed and network
+// errors with exponential backoff. Delays asked by the upstream are fed back
+// into the rate limiter so that other requests slow down too.
+func (c *BaseClient) callWithRetry(ctx context.Context, inputText string, fromLanguage string, toLanguage string, call completeFunc) (string, error) {
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			logger.Error("Rate limit exceeded",
+				zap.Error(err),
+				zap.String("Name", c.info.Name),
+				zap.String("Model", c.info.ModelName),
+				zap.String("Endpoint", c.info.Endpoint),
+			)
+			return "", fmt.Errorf("%w: %w", errLimiterWait, err)
+		}
+
+		hint := &retryHint{}
+		content, err := call(context.WithValue(ctx, retryHintKey{}, hint), inputText, fromLanguage, toLanguage)
+		if err == nil || attempt >= c.info.Retry.MaxAttempts || !isRetryable(err) || ctx.Err() != nil {
+			return content, err
+		}
+
+		if hint.delay > 0 {
+			// the next limiter.Wait blocks until the upstream is ready again
+			c.throttle(hint.delay)
+		} else if err := sleep(ctx, c.info.Retry.backoff(attempt)); err != nil {
+			return "", err
+		}
+		logger.Warn("Retrying upstream call",
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.Int("Attempt", attempt+1),
+			zap.Duration("RetryAfter", hint.delay),
+			zap.Error(err),
+		)
+	}
+}
+
+// userPrompt renders the configured prompt for a single translation,
+// followed by the translate options and the translation memory references of
+// the context.
+func (c *BaseClient) userPrompt(ctx context.Context, inputText string, fromLanguage string, toLanguage string) string {
+	return fmt.Sprintf(c.info.Prompt, fromLanguage, toLanguage, inputText) + optionsPrompt(ctx) + referencesPrompt(ctx)
+}
+
+// cleanContent strips the quotes and code fences models like to wrap around
+// their answers.
+func cleanContent(content string) string {
+	// remove the surrounding quotes if they exist
+	if len(content) >= 2 && strings.HasPrefix(content, "\"") && strings.HasSuffix(content, "\"") {
+		content = content[1 : len(content)-1]
+	}
+
+	if strings.HasPrefix(content, "```") && strings.HasSuffix(content, "```") {
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(content, "```")
+	}
+
+	if strings.HasPrefix(content, "'") && strings.HasSuffix(content, "'") {
+		content = strings.TrimPrefix(content, "'")
+		content = strings.TrimSuffix(content, "'")
+	}
+
+	return content
+}
+
 func NewBaseClient(info ClientInfo) *BaseClient {
 	cache := NewMemoryCache(time.Hour*time.Duration(info.CacheExpireHours), time.Minute*10)
-	return &BaseClient{info: info, limiter: rate.NewLimiter(rate.Limit(info.RateLimit), 1), cache: cache}
+	return &BaseClient{
+		info:    info,
+		limiter: rate.NewLimiter(rate.Limit(info.RateLimit), 1),
+		cache:   cache,
+		breaker: NewCircuitBreaker(info.Breaker),
+		flight:  &singleflight.Group{},
+
+		throttleMu: &sync.Mutex{},
+	}
 }
 
 type ClientManager struct {
 	clientsWithEndpoint map[string]Client
 	clientsWithName     map[string]Client
+	fallbacks           map[string][]string
+	hedges              map[string]HedgeOptions
+	latencyTrackers     map[string]*latencyTracker
+	routes              []Route
+	tm                  *TranslationMemory
+	tmOptions           TMOptions
 	mu                  sync.RWMutex
 }
 
 func NewClientManager() *ClientManager {
-	return &ClientManager{clientsWithEndpoint: make(map[string]Client), clientsWithName: make(map[string]Client)}
+	return &ClientManager{
+		clientsWithEndpoint: make(map[string]Client),
+		clientsWithName:     make(map[string]Client),
+		fallbacks:           make(map[string][]string),
+		hedges:              make(map[string]HedgeOptions),
+		latencyTrackers:     make(map[string]*latencyTracker),
+	}
 }
 
 func (m *ClientManager) AddClient(endpoint string, client Client) {
@@ -71,6 +284,126 @@ func (m *ClientManager) AddClient(endpoint string, client Client) {
 	m.clientsWithName[client.GetClientInfo().Name] = client
 }
 
+// SetFallbacks sets the names of the clients tried in order when the client
+// with the given name fails.
+func (m *ClientManager) SetFallbacks(name string, fallbacks []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fallbacks[name] = fallbacks
+}
+
+// SetHedge races the client with the given name against options.Secondary
+// when it is slower than usual.
+func (m *ClientManager) SetHedge(name string, options HedgeOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hedges[name] = options
+}
+
+// Complete translates the input with the given client and falls back to its
+// configured fallback clients on error, timeout or content block. It returns
+// the client that produced the translation.
+func (m *ClientManager) Complete(ctx context.Context, primary Client, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, Client, error) {
+	if !forceRefresh {
+		var translatedText string
+		var found bool
+		if translatedText, ctx, found = m.searchMemory(ctx, inputText, fromLanguage, toLanguage); found {
+			return translatedText, primary, nil
+		}
+	}
+
+	chain := m.chain(primary)
+	var lastErr error
+	for i, client := range chain {
+		translatedText, producer, err := m.attempt(ctx, client, inputText, fromLanguage, toLanguage, forceRefresh)
+		if err == nil {
+			if i > 0 {
+				logger.Info("Translated by fallback client",
+					zap.String("Name", primary.GetClientInfo().Name),
+					zap.String("Fallback", producer.GetClientInfo().Name),
+				)
+			}
+			m.learn(inputText, fromLanguage, toLanguage, translatedText)
+			return translatedText, producer, nil
+		}
+		lastErr = err
+
+		// the caller gave up, there is no point in trying the next client
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(chain)-1 {
+			logger.Warn("Client failed, trying fallback",
+				zap.String("Name", client.GetClientInfo().Name),
+				zap.String("Fallback", chain[i+1].GetClientInfo().Name),
+				zap.Error(err),
+			)
+		}
+	}
+	return "", primary, lastErr
+}
+
+// chain returns the primary client followed by its fallback clients.
+func (m *ClientManager) chain(primary Client) []Client {
+	chain := []Client{primary}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range m.fallbacks[primary.GetClientInfo().Name] {
+		if fallback, ok := m.clientsWithName[name]; ok {
+			chain = append(chain, fallback)
+		} else {
+			logger.Error("Fallback client not found", zap.String("Name", primary.GetClientInfo().Name), zap.String("Fallback", name))
+		}
+	}
+	return chain
+}
+
+// attempt translates the input with a single client of the fallback chain,
+// hedging it when configured.
+func (m *ClientManager) attempt(ctx context.Context, client Client, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, Client, error) {
+	m.mu.RLock()
+	options, hedged := m.hedges[client.GetClientInfo().Name]
+	secondary, found := m.clientsWithName[options.Secondary]
+	m.mu.RUnlock()
+
+	if hedged && found && hedging(ctx) {
+		return m.completeHedged(ctx, client, secondary, options, inputText, fromLanguage, toLanguage, forceRefresh)
+	}
+	translatedText, err := m.completeTracked(ctx, client, inputText, fromLanguage, toLanguage, forceRefresh)
+	return translatedText, client, err
+}
+
+// completeTracked records the latency of the successful requests for hedging.
+func (m *ClientManager) completeTracked(ctx context.Context, client Client, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	start := time.Now()
+	translatedText, err := completeWithTimeout(ctx, client, inputText, fromLanguage, toLanguage, forceRefresh)
+	if err == nil {
+		m.latencies(client).observe(time.Since(start))
+	}
+	return translatedText, err
+}
+
+func (m *ClientManager) latencies(client Client) *latencyTracker {
+	name := client.GetClientInfo().Name
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tracker, ok := m.latencyTrackers[name]
+	if !ok {
+		tracker = &latencyTracker{}
+		m.latencyTrackers[name] = tracker
+	}
+	return tracker
+}
+
+func completeWithTimeout(ctx context.Context, client Client, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	if timeout := client.GetClientInfo().Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return client.Complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh)
+}
+
 func (m *ClientManager) GetClientByEndpoint(endpoint string) (Client, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -101,6 +434,19 @@ func (m *ClientManager) GetAllEndpoints() []string {
 	return endpoints
 }
 
+// GetAllBreakerStates returns the circuit breaker state of every client by name.
+func (m *ClientManager) GetAllBreakerStates() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make(map[string]string, len(m.clientsWithName))
+	for name, client := range m.clientsWithName {
+		if stater, ok := client.(BreakerStater); ok {
+			states[name] = stater.BreakerState()
+		}
+	}
+	return states
+}
+
 func (m *ClientManager) GetAllNames() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
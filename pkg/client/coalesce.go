@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// coalesce runs call once for all the concurrent callers with the same cache
+// key, so that identical in-flight translations share one upstream request.
+// The call runs with the context of the first caller; when that caller gives
+// up, the others still waiting start a new call of their own.
+func (c *BaseClient) coalesce(ctx context.Context, cacheKey string, call func(ctx context.Context) (string, error)) (string, error) {
+	for {
+		led := false
+		results := c.flight.DoChan(cacheKey, func() (any, error) {
+			led = true
+			return call(ctx)
+		})
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case result := <-results:
+			if result.Shared {
+				logger.Debug("Coalesced request", zap.String("Name", c.info.Name), zap.String("Key", cacheKey))
+			}
+			if !led && result.Err != nil && isContextError(result.Err) && ctx.Err() == nil {
+				// the caller running the request was cancelled, not us
+				continue
+			}
+			content, _ := result.Val.(string)
+			return content, result.Err
+		}
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type DeepLClient struct {
+	BaseClient
+	apiKey string
+}
+
+type deepLRequest struct {
+	Text        []string `json:"text"`
+	SourceLang  string   `json:"source_lang,omitempty"`
+	TargetLang  string   `json:"target_lang"`
+	ModelType   string   `json:"model_type,omitempty"`
+	Formality   string   `json:"formality,omitempty"`
+	TagHandling string   `json:"tag_handling,omitempty"`
+}
+
+type deepLResponse struct {
+	Translations []struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	} `json:"translations"`
+}
+
+func NewDeepLClient(info ClientInfo, apiKey string) *DeepLClient {
+	return &DeepLClient{
+		BaseClient: *NewBaseClient(info),
+		apiKey:     apiKey,
+	}
+}
+
+func (c *DeepLClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call DeepL Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.translate)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *DeepLClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.translate), onDelta)
+}
+
+func (c *DeepLClient) translate(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	request := deepLRequest{
+		Text:       []string{inputText},
+		SourceLang: deepLLanguage(LanguageCode(fromLanguage), false),
+		TargetLang: deepLLanguage(LanguageCode(toLanguage), true),
+		ModelType:  c.info.ModelName,
+	}
+	options := translateOptions(ctx)
+	request.Formality, request.TagHandling = options.Formality, options.TagHandling
+	if request.TargetLang == "" {
+		return "", fmt.Errorf("unsupported target language for DeepL: %s", toLanguage)
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + c.apiKey}
+
+	var resp deepLResponse
+	url := strings.TrimSuffix(c.info.BaseURL, "/") + "/translate"
+	if err := doJSON(ctx, http.MethodPost, url, headers, request, &resp); err != nil {
+		logger.Error("DeepL Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	if len(resp.Translations) == 0 {
+		return "", fmt.Errorf("empty response from DeepL %s", c.info.Name)
+	}
+	return resp.Translations[0].Text, nil
+}
+
+func (c *DeepLClient) GetClientInfo() ClientInfo {
+	return c.info
+}
+
+// deepLLanguage converts a canonical language code to the one DeepL expects.
+// Only target languages distinguish between Chinese variants.
+func deepLLanguage(code string, target bool) string {
+	if target && code == "zh-TW" {
+		return "ZH-HANT"
+	}
+	base, _, _ := strings.Cut(code, "-")
+	return strings.ToUpper(base)
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages are the languages told apart by their script alone.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Tibetan, "bo"},
+}
+
+// traditionalHan and simplifiedHan are frequent characters written
+// differently in traditional and simplified Chinese.
+const (
+	traditionalHan = "這們個來說國會時為學對還體開後麼經與無點實現長問關從東見車門書話樣過給讓"
+	simplifiedHan  = "这们个来说国会时为学对还体开后么经与无点实现长问关从东见车门书话样过给让"
+)
+
+// latinStopwords are frequent words of the languages written in the Latin
+// script, and latinLetters their distinctive letters.
+var (
+	latinStopwords = map[string][]string{
+		"en": {"the", "and", "of", "to", "is", "in", "that", "it", "you", "for", "with", "this", "are", "was", "on", "be", "have", "not"},
+		"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "zu", "den", "mit", "sie", "ein", "eine", "auf", "es", "auch", "sich", "dem"},
+		"fr": {"le", "la", "les", "et", "est", "un", "une", "des", "du", "pas", "que", "je", "vous", "pour", "dans", "ce", "il", "sur"},
+		"es": {"el", "los", "las", "y", "es", "un", "una", "que", "de", "no", "por", "con", "para", "se", "lo", "del", "está", "como"},
+		"it": {"il", "lo", "gli", "e", "è", "di", "che", "non", "un", "una", "per", "con", "sono", "della", "del", "anche", "questo", "ma"},
+		"pt": {"o", "os", "as", "e", "é", "um", "uma", "que", "não", "de", "do", "da", "para", "com", "em", "se", "por", "mais"},
+		"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "ik", "je", "op", "te", "zijn", "met", "voor", "ook", "maar", "wat"},
+		"pl": {"i", "w", "nie", "na", "jest", "się", "to", "że", "z", "do", "jak", "co", "ale", "tak", "jestem", "przez", "dla", "od"},
+		"sv": {"och", "är", "att", "det", "som", "en", "ett", "inte", "jag", "med", "för", "på", "av", "har", "till", "den", "du", "om"},
+		"id": {"yang", "dan", "di", "ini", "itu", "dengan", "untuk", "tidak", "dari", "ada", "saya", "akan", "pada", "juga", "ke", "bisa", "anda", "kami"},
+		"vi": {"là", "và", "của", "có", "không", "tôi", "được", "một", "này", "cho", "người", "những", "đã", "với", "các", "trong", "để", "bạn"},
+		"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "ne", "var", "daha", "gibi", "ben", "sen", "olan", "değil", "mi", "ama"},
+	}
+	latinLetters = map[string]string{
+		"de": "ßäöü",
+		"fr": "çéèêëàâîïôûœ",
+		"es": "ñ¿¡áíóú",
+		"pt": "ãõçáâêô",
+		"pl": "ąęłńśźż",
+		"sv": "åäö",
+		"tr": "ğışçöü",
+		"cs": "řůěčšž",
+		"ro": "ăâîșț",
+		"hu": "őűáéö",
+		"vi": "ơưđạảấầẩẫậắằẳẵặẹẻẽếềểễệỉịọỏốồổỗộớờởỡợụủứừửữựỳỵỷỹ",
+	}
+)
+
+// DetectLanguage guesses the language code of a text from its script and, for
+// the Latin script, from its frequent words and distinctive letters. The
+// confidence is between 0 and 1, an empty code means the text has no letters
+// of a known script.
+func DetectLanguage(text string) (string, float64) {
+	var letters, han, kana, latin int
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			for _, script := range scriptLanguages {
+				if unicode.Is(script.table, r) {
+					scripts[script.code]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+
+	code, count := "", 0
+	for script, n := range scripts {
+		if n > count {
+			code, count = script, n
+		}
+	}
+	switch {
+	case kana > 0 && han+kana >= count && han+kana >= latin:
+		return "ja", float64(han+kana) / float64(letters)
+	case han > 0 && han >= count && han >= latin:
+		return chineseVariant(text), float64(han) / float64(letters)
+	case count > 0 && count >= latin:
+		if code == "ru" && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			code = "uk"
+		}
+		return code, float64(count) / float64(letters)
+	}
+	if latin == 0 {
+		return "", 0
+	}
+	code, confidence := detectLatin(text)
+	return code, confidence * float64(latin) / float64(letters)
+}
+
+func chineseVariant(text string) string {
+	traditional, simplified := 0, 0
+	for _, r := range text {
+		if strings.ContainsRune(traditionalHan, r) {
+			traditional++
+		} else if strings.ContainsRune(simplifiedHan, r) {
+			simplified++
+		}
+	}
+	if traditional > simplified {
+		return "zh-TW"
+	}
+	return "zh-CN"
+}
+
+// detectLatin scores the languages written in the Latin script, English being
+// the guess when nothing stands out.
+func detectLatin(text string) (string, float64) {
+	text = strings.ToLower(text)
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	scores := make(map[string]float64)
+	for language, stopwords := range latinStopwords {
+		for _, word := range words {
+			for _, stopword := range stopwords {
+				if word == stopword {
+					scores[language]++
+					break
+				}
+			}
+		}
+	}
+	for _, r := range text {
+		for language, letters := range latinLetters {
+			if strings.ContainsRune(letters, r) {
+				scores[language] += 0.5
+			}
+		}
+	}
+
+	best, total := "en", 0.0
+	for language, score := range scores {
+		total += score
+		if score > scores[best] || (score == scores[best] && language < best) {
+			best = language
+		}
+	}
+	if total == 0 {
+		return "en", 0.1
+	}
+	return best, scores[best] / total
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ContentBlockedError is returned when a model refuses to translate the input,
+// e.g. because of a safety filter.
+type ContentBlockedError struct {
+	ModelName string
+	Reason    string
+}
+
+func (e *ContentBlockedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("content blocked by model %s", e.ModelName)
+	}
+	return fmt.Sprintf("content blocked by model %s: %s", e.ModelName, e.Reason)
+}
+
+// IsContentBlocked reports whether err was caused by a model refusing the content.
+func IsContentBlocked(err error) bool {
+	var blocked *ContentBlockedError
+	return errors.As(err, &blocked)
+}
+
+// Refusal codes.
+const (
+	RefusalContentBlocked = "content_blocked"
+	RefusalInvalidRequest = "invalid_request"
+)
+
+// maxRefusalReason bounds the upstream answer kept as the reason of a rejected
+// request.
+const maxRefusalReason = 500
+
+// Refusal describes a translation refused by a model, or rejected by its
+// upstream as an invalid request. Sending the same input again fails the same
+// way, so refusals are cached for a short time.
+type Refusal struct {
+	Code           string `json:"code"`
+	Model          string `json:"model"`
+	Reason         string `json:"reason,omitempty"`
+	UpstreamStatus int    `json:"upstream_status,omitempty"`
+}
+
+// RefusedError is returned when a translation is refused, Cached telling
+// whether the refusal was served by the negative cache without calling the
+// upstream. It unwraps to the error of the upstream.
+type RefusedError struct {
+	Refusal Refusal
+	Cached  bool
+	Err     error
+}
+
+func (e *RefusedError) Error() string {
+	if e.Cached {
+		return e.Err.Error() + " (cached)"
+	}
+	return e.Err.Error()
+}
+
+func (e *RefusedError) Unwrap() error {
+	return e.Err
+}
+
+// AsRefusal returns the refusal err was caused by.
+func AsRefusal(err error) (*RefusedError, bool) {
+	var refused *RefusedError
+	if errors.As(err, &refused) {
+		return refused, true
+	}
+	return nil, false
+}
+
+// IsRefusal reports whether err was caused by a model refusing the content or
+// by an upstream rejecting the request, retrying the same input is pointless.
+func IsRefusal(err error) bool {
+	_, ok := refusalOf(err, "")
+	return ok
+}
+
+// refusalOf describes err as a refusal of the client with the given name.
+func refusalOf(err error, name string) (Refusal, bool) {
+	var blocked *ContentBlockedError
+	if errors.As(err, &blocked) {
+		return Refusal{Code: RefusalContentBlocked, Model: name, Reason: blocked.Reason}, true
+	}
+	switch status := upstreamStatusCode(err); status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		reason := upstreamMessage(err)
+		if len(reason) > maxRefusalReason {
+			reason = strings.ToValidUTF8(reason[:maxRefusalReason], "")
+		}
+		return Refusal{Code: RefusalInvalidRequest, Model: name, Reason: reason, UpstreamStatus: status}, true
+	}
+	return Refusal{}, false
+}
+
+// err rebuilds the upstream error of a cached refusal.
+func (r Refusal) err() error {
+	if r.Code == RefusalContentBlocked {
+		return &ContentBlockedError{ModelName: r.Model, Reason: r.Reason}
+	}
+	return &HTTPError{StatusCode: r.UpstreamStatus, Body: r.Reason}
+}
+
+// CircuitOpenError is returned without calling the upstream while the circuit
+// breaker of a client is open.
+type CircuitOpenError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker of %s is open, retry after %s", e.Name, e.RetryAfter.Round(time.Second))
+}
+
+// upstreamStatusCode returns the HTTP status code of a failed upstream call, or
+// 0 when err does not carry one.
+func upstreamStatusCode(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode
+	}
+	return 0
+}
+
+// upstreamMessage returns the error message of a failed upstream call.
+func upstreamMessage(err error) string {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return strings.TrimSpace(httpErr.Body)
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+	return err.Error()
+}
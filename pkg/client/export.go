@@ -0,0 +1,300 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// ExportFormats are the formats of the cache exports and imports: JSON lines
+// of CachedTranslation, CSV and TMX 1.4b.
+var ExportFormats = []string{"jsonl", "csv", "tmx"}
+
+const (
+	tmxDateFormat = "20060102T150405Z"
+	// tmxUndetermined is the language of the texts whose source language was
+	// detected automatically.
+	tmxUndetermined = "und"
+	maxJSONLineSize = 1024 * 1024
+)
+
+var csvHeader = []string{"model", "from", "to", "source", "translation", "created_at", "expires_at"}
+
+// TranslationWriter writes cached translations in an export format.
+type TranslationWriter interface {
+	Write(translation CachedTranslation) error
+	// Close ends the document, it does not close the underlying writer.
+	Close() error
+}
+
+func NewTranslationWriter(format string, w io.Writer) (TranslationWriter, error) {
+	switch format {
+	case "jsonl":
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{writer: writer}, nil
+	case "tmx":
+		return newTMXWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported export format: %s", format)
+}
+
+// ReadTranslations calls fn for every translation of a document in an export
+// format.
+func ReadTranslations(format string, r io.Reader, fn func(translation CachedTranslation) error) error {
+	switch format {
+	case "jsonl":
+		return readJSONL(r, fn)
+	case "csv":
+		return readCSV(r, fn)
+	case "tmx":
+		return readTMX(r, fn)
+	}
+	return fmt.Errorf("unsupported import format: %s", format)
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(translation CachedTranslation) error {
+	return w.encoder.Encode(translation)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+func readJSONL(r io.Reader, fn func(translation CachedTranslation) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var translation CachedTranslation
+		if err := json.Unmarshal(scanner.Bytes(), &translation); err != nil {
+			return fmt.Errorf("invalid line %d: %w", line, err)
+		}
+		if err := fn(translation); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// csvWriter writes the languages as language codes and the times in RFC 3339.
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(translation CachedTranslation) error {
+	return w.writer.Write([]string{
+		translation.Model,
+		exportLanguage(translation.From),
+		exportLanguage(translation.To),
+		translation.Source,
+		translation.Translation,
+		formatTime(translation.CreatedAt, time.RFC3339),
+		formatTime(translation.ExpiresAt, time.RFC3339),
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func readCSV(r io.Reader, fn func(translation CachedTranslation) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"source", "translation", "to"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("missing csv column: %s", required)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid csv: %w", err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		translation := CachedTranslation{CacheRecord: CacheRecord{
+			Model:       field("model"),
+			From:        importLanguage(field("from")),
+			To:          field("to"),
+			Source:      field("source"),
+			Translation: field("translation"),
+		}}
+		if translation.CreatedAt, err = parseTime(field("created_at"), time.RFC3339); err != nil {
+			return err
+		}
+		if translation.ExpiresAt, err = parseTime(field("expires_at"), time.RFC3339); err != nil {
+			return err
+		}
+		if err := fn(translation); err != nil {
+			return err
+		}
+	}
+}
+
+type tmxWriter struct {
+	w       io.Writer
+	encoder *xml.Encoder
+}
+
+type tmxOutputUnit struct {
+	XMLName      xml.Name           `xml:"tu"`
+	SrcLang      string             `xml:"srclang,attr"`
+	CreationDate string             `xml:"creationdate,attr,omitempty"`
+	CreationID   string             `xml:"creationid,attr,omitempty"`
+	Props        []tmxProp          `xml:"prop"`
+	Variants     []tmxOutputVariant `xml:"tuv"`
+}
+
+type tmxOutputVariant struct {
+	Lang    string `xml:"xml:lang,attr"`
+	Segment string `xml:"seg"`
+}
+
+func newTMXWriter(w io.Writer) (*tmxWriter, error) {
+	header := fmt.Sprintf(`%s<tmx version="1.4">
+<header creationtool="Polyglot-Gate-Server" creationtoolversion="1" segtype="sentence" o-tmf="Polyglot-Gate-Server" adminlang="en" srclang="*all*" datatype="plaintext" creationdate="%s"/>
+<body>
+`, xml.Header, time.Now().UTC().Format(tmxDateFormat))
+	if _, err := io.WriteString(w, header); err != nil {
+		return nil, err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return &tmxWriter{w: w, encoder: encoder}, nil
+}
+
+// Write writes a translation unit with the model and the expiration as
+// properties.
+func (w *tmxWriter) Write(translation CachedTranslation) error {
+	from := exportLanguage(translation.From)
+	unit := tmxOutputUnit{
+		SrcLang:      from,
+		CreationDate: formatTime(translation.CreatedAt, tmxDateFormat),
+		CreationID:   translation.Model,
+		Variants: []tmxOutputVariant{
+			{Lang: from, Segment: translation.Source},
+			{Lang: exportLanguage(translation.To), Segment: translation.Translation},
+		},
+	}
+	if translation.Model != "" {
+		unit.Props = append(unit.Props, tmxProp{Type: "x-model", Value: translation.Model})
+	}
+	if !translation.ExpiresAt.IsZero() {
+		unit.Props = append(unit.Props, tmxProp{Type: "x-expires", Value: formatTime(translation.ExpiresAt, tmxDateFormat)})
+	}
+	if err := w.encoder.Encode(unit); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "\n")
+	return err
+}
+
+func (w *tmxWriter) Close() error {
+	if err := w.encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "</body>\n</tmx>\n")
+	return err
+}
+
+// readTMX reads every variant of a unit as a translation of its source
+// variant, the model being the x-model property or the creation id.
+func readTMX(r io.Reader, fn func(translation CachedTranslation) error) error {
+	return decodeTMX(r, func(unit tmxUnit, source tmxVariant, target tmxVariant) error {
+		translation := CachedTranslation{CacheRecord: CacheRecord{
+			Model:       unit.CreationID,
+			From:        importLanguage(source.language()),
+			To:          target.language(),
+			Source:      string(source.Segment),
+			Translation: string(target.Segment),
+		}}
+		var err error
+		if translation.CreatedAt, err = parseTime(unit.CreationDate, tmxDateFormat); err != nil {
+			return err
+		}
+		for _, prop := range unit.Props {
+			switch prop.Type {
+			case "x-model":
+				translation.Model = prop.Value
+			case "x-expires":
+				if translation.ExpiresAt, err = parseTime(prop.Value, tmxDateFormat); err != nil {
+					return err
+				}
+			}
+		}
+		return fn(translation)
+	})
+}
+
+// exportLanguage returns the language code written in the exports.
+func exportLanguage(language string) string {
+	if code := LanguageCode(language); code != "" {
+		return code
+	}
+	return tmxUndetermined
+}
+
+func importLanguage(language string) string {
+	if language == tmxUndetermined {
+		return "auto"
+	}
+	return language
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+func parseTime(value string, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s: %w", value, err)
+	}
+	return t, nil
+}
+
+// IsExportFormat reports whether a format is supported by the exports and
+// imports.
+func IsExportFormat(format string) bool {
+	return slices.Contains(ExportFormats, format)
+}
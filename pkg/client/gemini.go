@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// geminiBlockedReasons are the finish reasons Gemini uses when it stops
+// generating because of its safety or policy filters.
+var geminiBlockedReasons = []string{"SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY"}
+
+type GeminiClient struct {
+	BaseClient
+	apiKey string
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     float32 `json:"temperature"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+func NewGeminiClient(info ClientInfo, apiKey string) *GeminiClient {
+	return &GeminiClient{
+		BaseClient: *NewBaseClient(info),
+		apiKey:     apiKey,
+	}
+}
+
+func (c *GeminiClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call Gemini Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.generateContent)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *GeminiClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.generateContent), onDelta)
+}
+
+func (c *GeminiClient) generateContent(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	request := geminiRequest{
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: c.userPrompt(ctx, inputText, fromLanguage, toLanguage)}}},
+		},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     c.info.Temperature,
+			MaxOutputTokens: c.info.MaxTokens,
+		},
+	}
+	if c.info.SystemPrompt != "" {
+		request.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: c.info.SystemPrompt}}}
+	}
+	headers := map[string]string{"x-goog-api-key": c.apiKey}
+
+	var resp geminiResponse
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", strings.TrimSuffix(c.info.BaseURL, "/"), url.PathEscape(c.info.ModelName))
+	if err := doJSON(ctx, http.MethodPost, endpoint, headers, request, &resp); err != nil {
+		logger.Error("Gemini Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	if resp.PromptFeedback.BlockReason != "" {
+		logger.Error("Prompt blocked by model",
+			zap.String("BlockReason", resp.PromptFeedback.BlockReason),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+		)
+		return "", &ContentBlockedError{ModelName: c.info.ModelName, Reason: resp.PromptFeedback.BlockReason}
+	}
+
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
+	}
+
+	candidate := resp.Candidates[0]
+	if slices.Contains(geminiBlockedReasons, candidate.FinishReason) {
+		logger.Error("Content blocked by model",
+			zap.String("FinishReason", candidate.FinishReason),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+		)
+		return "", &ContentBlockedError{ModelName: c.info.ModelName, Reason: candidate.FinishReason}
+	}
+	if candidate.FinishReason == "MAX_TOKENS" {
+		logger.Error("Response truncated by max tokens",
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.Int("MaxTokens", c.info.MaxTokens),
+		)
+		return "", fmt.Errorf("response truncated by max tokens from model %s", c.info.ModelName)
+	}
+
+	var content strings.Builder
+	for _, part := range candidate.Content.Parts {
+		content.WriteString(part.Text)
+	}
+	if content.Len() == 0 {
+		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
+	}
+
+	return cleanContent(content.String()), nil
+}
+
+func (c *GeminiClient) GetClientInfo() ClientInfo {
+	return c.info
+}
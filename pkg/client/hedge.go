@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	latencyWindowSize = 100
+	// minLatencySamples is the number of samples needed before the percentile
+	// is trusted over the configured minimum delay.
+	minLatencySamples = 10
+)
+
+// HedgeOptions configures the hedging of a client, applied to the
+// translations whose context enables it with WithHedging.
+type HedgeOptions struct {
+	Secondary  string        // name of the client raced against the primary
+	Percentile float64       // latency percentile of the primary after which the secondary is fired
+	MinDelay   time.Duration // lower bound of the hedge delay, also used until enough latencies are known
+}
+
+// latencyTracker keeps the latencies of the recent successful requests of a client.
+type latencyTracker struct {
+	samples []time.Duration
+	next    int
+	mu      sync.Mutex
+}
+
+func (t *latencyTracker) observe(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < latencyWindowSize {
+		t.samples = append(t.samples, latency)
+		return
+	}
+	t.samples[t.next] = latency
+	t.next = (t.next + 1) % latencyWindowSize
+}
+
+// percentile returns the given latency percentile, or false when there are
+// not enough samples yet.
+func (t *latencyTracker) percentile(percentile float64) (time.Duration, bool) {
+	t.mu.Lock()
+	sorted := slices.Clone(t.samples)
+	t.mu.Unlock()
+
+	if len(sorted) < minLatencySamples {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	index := int(float64(len(sorted)-1) * percentile / 100)
+	return sorted[index], true
+}
+
+type hedgingKey struct{}
+
+// WithHedging enables hedging for the translations of ctx. Hedging costs a
+// second upstream request for the slow translations, so only the endpoints
+// where latency matters more than cost opt in.
+func WithHedging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hedgingKey{}, true)
+}
+
+func hedging(ctx context.Context) bool {
+	enabled, _ := ctx.Value(hedgingKey{}).(bool)
+	return enabled
+}
+
+type hedgeResult struct {
+	translatedText string
+	client         Client
+	err            error
+}
+
+// completeHedged sends the request to primary and, if it has not answered
+// within the hedge delay, to secondary as well. The first successful answer
+// wins and the other request is cancelled.
+func (m *ClientManager) completeHedged(ctx context.Context, primary Client, secondary Client, options HedgeOptions, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, Client, error) {
+	delay := options.MinDelay
+	if latency, ok := m.latencies(primary).percentile(options.Percentile); ok && latency > delay {
+		delay = latency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	// cancels the loser once a result has been returned
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	run := func(client Client) {
+		translatedText, err := m.completeTracked(ctx, client, inputText, fromLanguage, toLanguage, forceRefresh)
+		results <- hedgeResult{translatedText: translatedText, client: client, err: err}
+	}
+
+	go run(primary)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case result := <-results:
+		return result.translatedText, result.client, result.err
+	case <-timer.C:
+	}
+
+	logger.Info("Hedging request",
+		zap.String("Name", primary.GetClientInfo().Name),
+		zap.String("Secondary", secondary.GetClientInfo().Name),
+		zap.Duration("Delay", delay),
+	)
+	go run(secondary)
+
+	first := <-results
+	if first.err == nil {
+		return first.translatedText, first.client, nil
+	}
+	second := <-results
+	if second.err == nil {
+		return second.translatedText, second.client, nil
+	}
+	if second.client == primary {
+		return "", primary, second.err
+	}
+	return "", primary, first.err
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+type HTTPTemplateOptions struct {
+	APIKey       string
+	Method       string
+	Headers      map[string]string
+	BodyTemplate string
+	ResponsePath string // gjson path of the translated text in the response
+}
+
+// HTTPTemplateClient calls an arbitrary translation API whose URL, headers and
+// body are rendered from Go templates.
+type HTTPTemplateClient struct {
+	BaseClient
+	options HTTPTemplateOptions
+	url     *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+}
+
+// httpTemplateData is the data passed to the URL, header and body templates.
+type httpTemplateData struct {
+	Text      string
+	From      string
+	To        string
+	FromCode  string
+	ToCode    string
+	ModelName string
+	APIKey    string
+}
+
+var httpTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {"q": {{json .Text}}}
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func NewHTTPTemplateClient(info ClientInfo, options HTTPTemplateOptions) (*HTTPTemplateClient, error) {
+	if options.Method == "" {
+		options.Method = http.MethodPost
+	}
+	options.Method = strings.ToUpper(options.Method)
+
+	urlTemplate, headers, bodyTemplate, err := parseHTTPTemplates(info.BaseURL, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPTemplateClient{
+		BaseClient: *NewBaseClient(info),
+		options:    options,
+		url:        urlTemplate,
+		headers:    headers,
+		body:       bodyTemplate,
+	}, nil
+}
+
+// ValidateHTTPTemplates parses the URL, header and body templates of an HTTP
+// template client without creating it.
+func ValidateHTTPTemplates(baseURL string, options HTTPTemplateOptions) error {
+	_, _, _, err := parseHTTPTemplates(baseURL, options)
+	return err
+}
+
+func parseHTTPTemplates(baseURL string, options HTTPTemplateOptions) (*template.Template, map[string]*template.Template, *template.Template, error) {
+	urlTemplate, err := template.New("url").Funcs(httpTemplateFuncs).Parse(baseURL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid url template: %w", err)
+	}
+	bodyTemplate, err := template.New("body").Funcs(httpTemplateFuncs).Parse(options.BodyTemplate)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid body template: %w", err)
+	}
+	headers := make(map[string]*template.Template, len(options.Headers))
+	for key, value := range options.Headers {
+		headerTemplate, err := template.New(key).Funcs(httpTemplateFuncs).Parse(value)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid header template %s: %w", key, err)
+		}
+		headers[key] = headerTemplate
+	}
+	return urlTemplate, headers, bodyTemplate, nil
+}
+
+func (c *HTTPTemplateClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call HTTP template Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.translate)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *HTTPTemplateClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.translate), onDelta)
+}
+
+func (c *HTTPTemplateClient) translate(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	data := httpTemplateData{
+		Text:      inputText,
+		From:      fromLanguage,
+		To:        toLanguage,
+		FromCode:  LanguageCode(fromLanguage),
+		ToCode:    LanguageCode(toLanguage),
+		ModelName: c.info.ModelName,
+		APIKey:    c.options.APIKey,
+	}
+
+	req, err := c.newRequest(ctx, data)
+	if err != nil {
+		logger.Error("Failed to render HTTP template", zap.Error(err), zap.String("Name", c.info.Name))
+		return "", err
+	}
+
+	var raw json.RawMessage
+	if err := doRequest(req, &raw); err != nil {
+		logger.Error("HTTP template Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	result := gjson.GetBytes(raw, c.options.ResponsePath)
+	if !result.Exists() || result.String() == "" {
+		logger.Error("Translated text not found in response",
+			zap.String("Name", c.info.Name),
+			zap.String("ResponsePath", c.options.ResponsePath),
+			zap.ByteString("Response", raw),
+		)
+		return "", fmt.Errorf("empty response from %s at path %s", c.info.Name, c.options.ResponsePath)
+	}
+	return result.String(), nil
+}
+
+func (c *HTTPTemplateClient) newRequest(ctx context.Context, data httpTemplateData) (*http.Request, error) {
+	var url strings.Builder
+	if err := c.url.Execute(&url, data); err != nil {
+		return nil, fmt.Errorf("failed to render url: %w", err)
+	}
+
+	var body io.Reader
+	if c.options.BodyTemplate != "" {
+		var buf bytes.Buffer
+		if err := c.body.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render body: %w", err)
+		}
+		body = &buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.options.Method, url.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, headerTemplate := range c.headers {
+		var value strings.Builder
+		if err := headerTemplate.Execute(&value, data); err != nil {
+			return nil, fmt.Errorf("failed to render header %s: %w", key, err)
+		}
+		req.Header.Set(key, value.String())
+	}
+	return req, nil
+}
+
+func (c *HTTPTemplateClient) GetClientInfo() ClientInfo {
+	return c.info
+}
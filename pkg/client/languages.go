@@ -0,0 +1,195 @@
+package client
+
+import (
+	"slices"
+	"strings"
+)
+
+// languageCodes maps the language names and codes used by the gateway's
+// callers (frontend, hcfy, DeepLX) to a canonical ISO 639-1 based code.
+var languageCodes = map[string]string{
+	"auto":                 "",
+	"auto detect":          "",
+	"自动检测":                 "",
+	"en":                   "en",
+	"en-us":                "en",
+	"en-gb":                "en",
+	"english":              "en",
+	"british english":      "en",
+	"american english":     "en",
+	"英语":                   "en",
+	"zh":                   "zh-CN",
+	"zh-cn":                "zh-CN",
+	"zh-hans":              "zh-CN",
+	"chinese":              "zh-CN",
+	"chinese(simplified)":  "zh-CN",
+	"simplified chinese":   "zh-CN",
+	"简体中文":                 "zh-CN",
+	"中文":                   "zh-CN",
+	"中文(简体)":               "zh-CN",
+	"zh-tw":                "zh-TW",
+	"zh-hant":              "zh-TW",
+	"chinese(traditional)": "zh-TW",
+	"traditional chinese":  "zh-TW",
+	"繁体中文":                 "zh-TW",
+	"中文(繁体)":               "zh-TW",
+	"ja":                   "ja",
+	"japanese":             "ja",
+	"日语":                   "ja",
+	"ko":                   "ko",
+	"korean":               "ko",
+	"韩语":                   "ko",
+	"de":                   "de",
+	"german":               "de",
+	"德语":                   "de",
+	"fr":                   "fr",
+	"french":               "fr",
+	"法语":                   "fr",
+	"es":                   "es",
+	"spanish":              "es",
+	"西班牙语":                 "es",
+	"it":                   "it",
+	"italian":              "it",
+	"意大利语":                 "it",
+	"pt":                   "pt",
+	"pt-br":                "pt",
+	"pt-pt":                "pt",
+	"portuguese":           "pt",
+	"brazilian portuguese": "pt",
+	"european portuguese":  "pt",
+	"葡萄牙语":                 "pt",
+	"ru":                   "ru",
+	"russian":              "ru",
+	"俄语":                   "ru",
+	"nl":                   "nl",
+	"dutch":                "nl",
+	"pl":                   "pl",
+	"polish":               "pl",
+	"bg":                   "bg",
+	"bulgarian":            "bg",
+	"cs":                   "cs",
+	"czech":                "cs",
+	"da":                   "da",
+	"danish":               "da",
+	"el":                   "el",
+	"greek":                "el",
+	"et":                   "et",
+	"estonian":             "et",
+	"fi":                   "fi",
+	"finnish":              "fi",
+	"hu":                   "hu",
+	"hungarian":            "hu",
+	"lt":                   "lt",
+	"lithuanian":           "lt",
+	"lv":                   "lv",
+	"latvian":              "lv",
+	"ro":                   "ro",
+	"romanian":             "ro",
+	"sk":                   "sk",
+	"slovak":               "sk",
+	"sl":                   "sl",
+	"slovenian":            "sl",
+	"nb":                   "nb",
+	"no":                   "nb",
+	"norwegian":            "nb",
+	"sv":                   "sv",
+	"swedish":              "sv",
+	"uk":                   "uk",
+	"ukrainian":            "uk",
+	"tr":                   "tr",
+	"turkish":              "tr",
+	"ar":                   "ar",
+	"arabic":               "ar",
+	"id":                   "id",
+	"indonesian":           "id",
+	"vi":                   "vi",
+	"vietnamese":           "vi",
+	"th":                   "th",
+	"thai":                 "th",
+	"he":                   "he",
+	"iw":                   "he",
+	"hebrew":               "he",
+	"hi":                   "hi",
+	"hindi":                "hi",
+	"bo":                   "bo",
+	"tibetan":              "bo",
+	"yue":                  "yue",
+	"cantonese":            "yue",
+	"粤语":                   "yue",
+	"lzh":                  "lzh",
+	"classical chinese":    "lzh",
+	"文言文":                  "lzh",
+}
+
+// languageNames are the English names of the canonical codes, as passed to
+// the models.
+var languageNames = map[string]string{
+	"ar":    "Arabic",
+	"bg":    "Bulgarian",
+	"bo":    "Tibetan",
+	"cs":    "Czech",
+	"da":    "Danish",
+	"de":    "German",
+	"el":    "Greek",
+	"en":    "English",
+	"es":    "Spanish",
+	"et":    "Estonian",
+	"fi":    "Finnish",
+	"fr":    "French",
+	"he":    "Hebrew",
+	"hi":    "Hindi",
+	"hu":    "Hungarian",
+	"id":    "Indonesian",
+	"it":    "Italian",
+	"ja":    "Japanese",
+	"ko":    "Korean",
+	"lt":    "Lithuanian",
+	"lv":    "Latvian",
+	"lzh":   "Classical Chinese",
+	"nb":    "Norwegian",
+	"nl":    "Dutch",
+	"pl":    "Polish",
+	"pt":    "Portuguese",
+	"ro":    "Romanian",
+	"ru":    "Russian",
+	"sk":    "Slovak",
+	"sl":    "Slovenian",
+	"sv":    "Swedish",
+	"th":    "Thai",
+	"tr":    "Turkish",
+	"uk":    "Ukrainian",
+	"vi":    "Vietnamese",
+	"yue":   "Cantonese",
+	"zh-CN": "Chinese(Simplified)",
+	"zh-TW": "Chinese(Traditional)",
+}
+
+// LanguageCode returns the canonical code (e.g. "en", "zh-CN") of a language
+// name or code. "auto" returns an empty code, unknown names are returned
+// lower-cased so that plain ISO codes still pass through.
+func LanguageCode(language string) string {
+	key := strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[key]; ok {
+		return code
+	}
+	return key
+}
+
+// LanguageName returns the English name of a language code, or the code
+// itself when it is unknown.
+func LanguageName(code string) string {
+	if name, ok := languageNames[LanguageCode(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// LanguageCodes returns the sorted canonical codes of the known languages.
+func LanguageCodes() []string {
+	codes := make([]string, 0, len(languageNames))
+	for code := range languageNames {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
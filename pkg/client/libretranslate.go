@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type LibreTranslateClient struct {
+	BaseClient
+	apiKey string
+}
+
+type libreTranslateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+	TranslatedText string `json:"translatedText"`
+}
+
+func NewLibreTranslateClient(info ClientInfo, apiKey string) *LibreTranslateClient {
+	return &LibreTranslateClient{
+		BaseClient: *NewBaseClient(info),
+		apiKey:     apiKey,
+	}
+}
+
+func (c *LibreTranslateClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call LibreTranslate Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.translate)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *LibreTranslateClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.translate), onDelta)
+}
+
+func (c *LibreTranslateClient) translate(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	request := libreTranslateRequest{
+		Q:      inputText,
+		Source: libreTranslateLanguage(LanguageCode(fromLanguage)),
+		Target: libreTranslateLanguage(LanguageCode(toLanguage)),
+		Format: "text",
+		APIKey: c.apiKey,
+	}
+	if request.Target == "auto" {
+		return "", fmt.Errorf("unsupported target language for LibreTranslate: %s", toLanguage)
+	}
+
+	var resp libreTranslateResponse
+	url := strings.TrimSuffix(c.info.BaseURL, "/") + "/translate"
+	if err := doJSON(ctx, http.MethodPost, url, nil, request, &resp); err != nil {
+		logger.Error("LibreTranslate Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	if resp.TranslatedText == "" {
+		return "", fmt.Errorf("empty response from LibreTranslate %s", c.info.Name)
+	}
+	return resp.TranslatedText, nil
+}
+
+func (c *LibreTranslateClient) GetClientInfo() ClientInfo {
+	return c.info
+}
+
+// libreTranslateLanguage converts a canonical language code to the one
+// LibreTranslate expects.
+func libreTranslateLanguage(code string) string {
+	switch code {
+	case "":
+		return "auto"
+	case "zh-CN":
+		return "zh"
+	case "zh-TW":
+		return "zt"
+	}
+	return code
+}
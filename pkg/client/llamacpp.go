@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type LlamaCppClient struct {
+	BaseClient
+}
+
+type llamaCppCompletionRequest struct {
+	Prompt      string  `json:"prompt"`
+	NPredict    int     `json:"n_predict,omitempty"`
+	Temperature float32 `json:"temperature"`
+	Stream      bool    `json:"stream"`
+	CachePrompt bool    `json:"cache_prompt"`
+}
+
+type llamaCppCompletionResponse struct {
+	Content      string `json:"content"`
+	StoppedLimit bool   `json:"stopped_limit"`
+}
+
+type llamaCppHealthResponse struct {
+	Status string `json:"status"`
+}
+
+func NewLlamaCppClient(info ClientInfo) *LlamaCppClient {
+	return &LlamaCppClient{BaseClient: *NewBaseClient(info)}
+}
+
+func (c *LlamaCppClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call llama.cpp Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.completion)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *LlamaCppClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.completion), onDelta)
+}
+
+func (c *LlamaCppClient) completion(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	prompt := c.userPrompt(ctx, inputText, fromLanguage, toLanguage)
+	if c.info.SystemPrompt != "" {
+		prompt = c.info.SystemPrompt + "\n\n" + prompt
+	}
+
+	request := llamaCppCompletionRequest{
+		Prompt:      prompt,
+		NPredict:    c.info.MaxTokens,
+		Temperature: c.info.Temperature,
+		Stream:      false,
+		CachePrompt: true,
+	}
+
+	var resp llamaCppCompletionResponse
+	if err := doJSON(ctx, http.MethodPost, c.url("/completion"), nil, request, &resp); err != nil {
+		logger.Error("llama.cpp Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	if resp.StoppedLimit {
+		logger.Error("Response truncated by max tokens",
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.Int("MaxTokens", c.info.MaxTokens),
+		)
+		return "", fmt.Errorf("response truncated by max tokens from model %s", c.info.ModelName)
+	}
+
+	content := strings.TrimSpace(resp.Content)
+	if content == "" {
+		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
+	}
+
+	return cleanContent(content), nil
+}
+
+// CheckHealth verifies that the llama.cpp server is up and has its model loaded.
+func (c *LlamaCppClient) CheckHealth(ctx context.Context) error {
+	var health llamaCppHealthResponse
+	if err := doJSON(ctx, http.MethodGet, c.url("/health"), nil, nil, &health); err != nil {
+		return fmt.Errorf("llama.cpp server %s is not ready: %w", c.info.BaseURL, err)
+	}
+	if health.Status != "ok" {
+		return fmt.Errorf("llama.cpp server %s is not ready: %s", c.info.BaseURL, health.Status)
+	}
+	return nil
+}
+
+func (c *LlamaCppClient) url(path string) string {
+	return strings.TrimSuffix(c.info.BaseURL, "/") + path
+}
+
+func (c *LlamaCppClient) GetClientInfo() ClientInfo {
+	return c.info
+}
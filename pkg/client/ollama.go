@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type OllamaOptions struct {
+	KeepAlive string
+	NumCtx    int
+	AutoPull  bool
+}
+
+type OllamaClient struct {
+	BaseClient
+	options OllamaOptions
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaModelOptions struct {
+	Temperature float32 `json:"temperature"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model     string             `json:"model"`
+	Messages  []ollamaMessage    `json:"messages"`
+	Stream    bool               `json:"stream"`
+	KeepAlive string             `json:"keep_alive,omitempty"`
+	Options   ollamaModelOptions `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
+
+type ollamaPullResponse struct {
+	Status string `json:"status"`
+}
+
+func NewOllamaClient(info ClientInfo, options OllamaOptions) *OllamaClient {
+	return &OllamaClient{
+		BaseClient: *NewBaseClient(info),
+		options:    options,
+	}
+}
+
+func (c *OllamaClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	logger.Debug("Call Ollama Complete",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
+
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.chat)
+}
+
+// CompleteStream passes the whole translation to onDelta at once.
+func (c *OllamaClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, wholeStream(c.chat), onDelta)
+}
+
+func (c *OllamaClient) chat(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	messages := []ollamaMessage{}
+	if c.info.SystemPrompt != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: c.info.SystemPrompt})
+	}
+	messages = append(messages, ollamaMessage{Role: "user", Content: c.userPrompt(ctx, inputText, fromLanguage, toLanguage)})
+
+	request := ollamaChatRequest{
+		Model:     c.info.ModelName,
+		Messages:  messages,
+		Stream:    false,
+		KeepAlive: c.options.KeepAlive,
+		Options: ollamaModelOptions{
+			Temperature: c.info.Temperature,
+			NumCtx:      c.options.NumCtx,
+			NumPredict:  c.info.MaxTokens,
+		},
+	}
+
+	var resp ollamaChatResponse
+	if err := doJSON(ctx, http.MethodPost, c.url("/api/chat"), nil, request, &resp); err != nil {
+		logger.Error("Ollama Complete failed",
+			zap.Error(err),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
+		)
+		return "", err
+	}
+
+	if resp.DoneReason == "length" {
+		logger.Error("Response truncated by max tokens",
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.Int("MaxTokens", c.info.MaxTokens),
+		)
+		return "", fmt.Errorf("response truncated by max tokens from model %s", c.info.ModelName)
+	}
+
+	if resp.Message.Content == "" {
+		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
+	}
+
+	return cleanContent(resp.Message.Content), nil
+}
+
+// CheckHealth verifies that the configured model is present on the Ollama
+// server, pulling it first when AutoPull is enabled.
+func (c *OllamaClient) CheckHealth(ctx context.Context) error {
+	var tags ollamaTagsResponse
+	if err := doJSON(ctx, http.MethodGet, c.url("/api/tags"), nil, nil, &tags); err != nil {
+		return fmt.Errorf("failed to list ollama models: %w", err)
+	}
+
+	for _, model := range tags.Models {
+		if ollamaModelMatches(model.Name, c.info.ModelName) || ollamaModelMatches(model.Model, c.info.ModelName) {
+			return nil
+		}
+	}
+
+	if !c.options.AutoPull {
+		return fmt.Errorf("model %s not found on ollama server %s", c.info.ModelName, c.info.BaseURL)
+	}
+
+	logger.Info("Pulling ollama model", zap.String("Name", c.info.Name), zap.String("Model", c.info.ModelName))
+	var pull ollamaPullResponse
+	request := map[string]any{"model": c.info.ModelName, "stream": false}
+	if err := doJSON(ctx, http.MethodPost, c.url("/api/pull"), nil, request, &pull); err != nil {
+		return fmt.Errorf("failed to pull model %s: %w", c.info.ModelName, err)
+	}
+	logger.Info("Ollama model pulled", zap.String("Model", c.info.ModelName), zap.String("Status", pull.Status))
+	if pull.Status != "success" {
+		return fmt.Errorf("failed to pull model %s: %s", c.info.ModelName, pull.Status)
+	}
+	return nil
+}
+
+func (c *OllamaClient) url(path string) string {
+	return strings.TrimSuffix(c.info.BaseURL, "/") + path
+}
+
+func (c *OllamaClient) GetClientInfo() ClientInfo {
+	return c.info
+}
+
+// ollamaModelMatches compares model names, treating a missing tag as "latest".
+func ollamaModelMatches(name string, want string) bool {
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	if !strings.Contains(want, ":") {
+		want += ":latest"
+	}
+	return name == want
+}
@@ -2,33 +2,32 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
-	"golang.org/x/time/rate"
 )
 
 type OpenAIClient struct {
 	BaseClient
-	client *openai.Client
-	apiKey string
+	client   *openai.Client
+	clientMu sync.RWMutex
+	apiKey   string
 }
 
 func NewOpenAIClient(info ClientInfo, apiKey string) *OpenAIClient {
 	openaiConfig := openai.DefaultConfig(apiKey)
 	openaiConfig.BaseURL = info.BaseURL
+	openaiConfig.HTTPClient = defaultHTTPClient
 
 	return &OpenAIClient{
-		BaseClient: BaseClient{
-			info:    info,
-			limiter: rate.NewLimiter(rate.Limit(info.RateLimit), 1),
-			cache:   NewMemoryCache(time.Hour*time.Duration(info.CacheExpireHours), time.Minute*10),
-		},
-		apiKey: apiKey,
-		client: openai.NewClientWithConfig(openaiConfig),
+		BaseClient: *NewBaseClient(info),
+		apiKey:     apiKey,
+		client:     openai.NewClientWithConfig(openaiConfig),
 	}
 }
 
@@ -41,33 +40,41 @@ func (c *OpenAIClient) Complete(ctx context.Context, inputText string, fromLangu
 		zap.String("ToLanguage", toLanguage),
 	)
 
-	cacheKey := fmt.Sprintf("%s_%s_%s_%s_%s", c.info.Name, c.info.ModelName, fromLanguage, toLanguage, inputText)
+	return c.complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.createChatCompletion)
+}
 
-	if !forceRefresh {
-		if cached, err := c.cache.Get(cacheKey); err == nil {
-			logger.Debug("Cache hit", zap.String("Key", cacheKey))
-			return cached, nil
-		}
-	}
+func (c *OpenAIClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	logger.Debug("Call OpenAI CompleteStream",
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Endpoint", c.info.Endpoint),
+		zap.String("FromLanguage", fromLanguage),
+		zap.String("ToLanguage", toLanguage),
+	)
 
-	if err := c.limiter.Wait(ctx); err != nil {
-		logger.Error("OpenAI rate limit exceeded",
-			zap.Error(err),
-			zap.String("Name", c.info.Name),
-			zap.String("Model", c.info.ModelName),
-			zap.String("Endpoint", c.info.Endpoint),
-		)
-		return "", err
+	return c.completeStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, c.createChatCompletionStream, onDelta)
+}
+
+func (c *OpenAIClient) chatCompletionRequest(ctx context.Context, inputText string, fromLanguage string, toLanguage string) openai.ChatCompletionRequest {
+	messages := []openai.ChatCompletionMessage{}
+	if c.info.SystemPrompt != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: c.info.SystemPrompt})
 	}
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: c.userPrompt(ctx, inputText, fromLanguage, toLanguage),
+	})
 
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: c.info.ModelName,
-		Messages: []openai.ChatCompletionMessage{
-			{Role: openai.ChatMessageRoleUser, Content: fmt.Sprintf(c.info.Prompt, fromLanguage, toLanguage, inputText)},
-		},
+	return openai.ChatCompletionRequest{
+		Model:       c.info.ModelName,
+		Messages:    messages,
 		Temperature: c.info.Temperature,
 		MaxTokens:   c.info.MaxTokens,
-	})
+	}
+}
+
+func (c *OpenAIClient) createChatCompletion(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+	resp, err := c.openaiClient().CreateChatCompletion(ctx, c.chatCompletionRequest(ctx, inputText, fromLanguage, toLanguage))
 	if err != nil {
 		logger.Error("OpenAI Complete failed",
 			zap.Error(err),
@@ -85,38 +92,108 @@ func (c *OpenAIClient) Complete(ctx context.Context, inputText string, fromLangu
 		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
 	}
 
-	content := resp.Choices[0].Message.Content
-	// 检查内容是否为空或包含错误信息
-	if strings.Contains(content, "内容由于不合规被停止生成") {
-		logger.Error("Content blocked by model",
-			zap.String("Content", content),
+	choice := resp.Choices[0]
+	if err := c.checkFinishReason(choice.FinishReason); err != nil {
+		return "", err
+	}
+	return c.checkContent(choice.Message.Content)
+}
+
+// createChatCompletionStream streams the chat completion, passing the content
+// deltas to onDelta. The deltas are raw, only the returned translation is
+// cleaned.
+func (c *OpenAIClient) createChatCompletionStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, onDelta DeltaFunc) (string, error) {
+	stream, err := c.openaiClient().CreateChatCompletionStream(ctx, c.chatCompletionRequest(ctx, inputText, fromLanguage, toLanguage))
+	if err != nil {
+		logger.Error("OpenAI CompleteStream failed",
+			zap.Error(err),
 			zap.String("Name", c.info.Name),
 			zap.String("Model", c.info.ModelName),
 			zap.String("Endpoint", c.info.Endpoint),
+			zap.String("FromLanguage", fromLanguage),
+			zap.String("ToLanguage", toLanguage),
 		)
-		return "", fmt.Errorf("content blocked by model %s", c.info.ModelName)
+		return "", err
 	}
+	defer stream.Close()
 
-	// remove the surrounding quotes if they exist
-	if strings.HasPrefix(content, "\"") && strings.HasSuffix(content, "\"") {
-		content = content[1 : len(content)-1]
+	var content strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.Error("OpenAI stream failed",
+				zap.Error(err),
+				zap.String("Name", c.info.Name),
+				zap.String("Model", c.info.ModelName),
+				zap.String("Endpoint", c.info.Endpoint),
+			)
+			return "", err
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+
+		choice := resp.Choices[0]
+		if delta := choice.Delta.Content; delta != "" {
+			content.WriteString(delta)
+			if err := onDelta(delta); err != nil {
+				return "", err
+			}
+		}
+		if err := c.checkFinishReason(choice.FinishReason); err != nil {
+			return "", err
+		}
 	}
 
-	if strings.HasPrefix(content, "```") && strings.HasSuffix(content, "```") {
-		content = strings.TrimPrefix(content, "```")
-		content = strings.TrimSuffix(content, "```")
+	if content.Len() == 0 {
+		return "", fmt.Errorf("empty response from model %s", c.info.ModelName)
 	}
+	return c.checkContent(content.String())
+}
 
-	if strings.HasPrefix(content, "'") && strings.HasSuffix(content, "'") {
-		content = strings.TrimPrefix(content, "'")
-		content = strings.TrimSuffix(content, "'")
+func (c *OpenAIClient) checkFinishReason(finishReason openai.FinishReason) error {
+	if finishReason != openai.FinishReasonContentFilter {
+		return nil
 	}
+	logger.Error("Content blocked by model",
+		zap.String("FinishReason", string(finishReason)),
+		zap.String("Name", c.info.Name),
+		zap.String("Model", c.info.ModelName),
+		zap.String("Endpoint", c.info.Endpoint),
+	)
+	return &ContentBlockedError{ModelName: c.info.ModelName, Reason: string(finishReason)}
+}
 
-	if err := c.cache.Set(cacheKey, content, time.Hour*time.Duration(c.info.CacheExpireHours)); err != nil {
-		logger.Warn("Failed to set cache", zap.Error(err), zap.String("Key", cacheKey))
+// checkContent detects the block messages returned in place of a translation
+// and cleans the translation.
+func (c *OpenAIClient) checkContent(content string) (string, error) {
+	// 部分兼容服务不设置 finish_reason，而是直接返回拦截提示
+	if strings.Contains(content, "内容由于不合规被停止生成") {
+		logger.Error("Content blocked by model",
+			zap.String("Content", content),
+			zap.String("Name", c.info.Name),
+			zap.String("Model", c.info.ModelName),
+			zap.String("Endpoint", c.info.Endpoint),
+		)
+		return "", &ContentBlockedError{ModelName: c.info.ModelName, Reason: content}
 	}
 
-	return content, nil
+	return cleanContent(content), nil
+}
+
+func (c *OpenAIClient) openaiClient() *openai.Client {
+	c.clientMu.RLock()
+	defer c.clientMu.RUnlock()
+	return c.client
+}
+
+func (c *OpenAIClient) setOpenAIClient(client *openai.Client) {
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+	c.client = client
 }
 
 func (c *OpenAIClient) GetClientInfo() ClientInfo {
@@ -0,0 +1,45 @@
+package client
+
+import "context"
+
+// TranslateOptions tune a translation beyond its languages, as asked by the
+// DeepL compatible API. The LLM clients follow them through their prompt and
+// the DeepL client passes them on.
+type TranslateOptions struct {
+	Formality   string // default, more, less, prefer_more or prefer_less
+	TagHandling string // xml or html, the markup is kept as is
+}
+
+type translateOptionsKey struct{}
+
+// WithTranslateOptions attaches the options of a translation to its context.
+func WithTranslateOptions(ctx context.Context, options TranslateOptions) context.Context {
+	if options.Formality == "default" {
+		options.Formality = ""
+	}
+	return context.WithValue(ctx, translateOptionsKey{}, options)
+}
+
+func translateOptions(ctx context.Context) TranslateOptions {
+	options, _ := ctx.Value(translateOptionsKey{}).(TranslateOptions)
+	return options
+}
+
+// optionsPrompt renders the options attached to the context as instructions.
+func optionsPrompt(ctx context.Context) string {
+	options := translateOptions(ctx)
+	var prompt string
+	switch options.Formality {
+	case "more", "prefer_more":
+		prompt += "\n\nUse a formal tone."
+	case "less", "prefer_less":
+		prompt += "\n\nUse an informal tone."
+	}
+	switch options.TagHandling {
+	case "xml":
+		prompt += "\n\nThe text contains XML markup, keep the tags and their attributes unchanged and only translate the text between them."
+	case "html":
+		prompt += "\n\nThe text contains HTML markup, keep the tags and their attributes unchanged and only translate the text between them."
+	}
+	return prompt
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	BalanceRoundRobin       = "round_robin"
+	BalanceLeastOutstanding = "least_outstanding"
+	BalanceWeighted         = "weighted"
+)
+
+var BalanceStrategies = []string{BalanceRoundRobin, BalanceLeastOutstanding, BalanceWeighted}
+
+type PoolOptions struct {
+	Strategy      string
+	MaxFailures   int           // consecutive failures before a member is ejected
+	EjectDuration time.Duration // how long an ejected member is skipped
+}
+
+// PoolMember is a single upstream of a pool, e.g. one API key.
+type PoolMember struct {
+	Client Client
+	Weight int
+
+	outstanding   int
+	failures      int
+	currentWeight int
+	ejectedUntil  time.Time
+}
+
+// PoolClient balances one logical model over several upstream clients, each
+// keeping its own rate limiter. Members share the pool's cache.
+type PoolClient struct {
+	BaseClient
+	members []*PoolMember
+	options PoolOptions
+	next    int
+	mu      sync.Mutex
+}
+
+func NewPoolClient(info ClientInfo, members []*PoolMember, options PoolOptions) (*PoolClient, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("pool %s has no members", info.Name)
+	}
+	if options.Strategy == "" {
+		options.Strategy = BalanceRoundRobin
+	}
+	if options.MaxFailures <= 0 {
+		options.MaxFailures = 3
+	}
+	if options.EjectDuration <= 0 {
+		options.EjectDuration = 30 * time.Second
+	}
+
+	c := &PoolClient{
+		BaseClient: *NewBaseClient(info),
+		members:    members,
+		options:    options,
+	}
+	for _, member := range members {
+		if member.Weight <= 0 {
+			member.Weight = 1
+		}
+	}
+	c.SetCache(c.cache)
+	return c, nil
+}
+
+// SetCache sets the cache of the pool and of its members.
+func (c *PoolClient) SetCache(cache Cache) {
+	c.cache = cache
+	for _, member := range c.members {
+		if setter, ok := member.Client.(CacheHolder); ok {
+			setter.SetCache(cache)
+		}
+	}
+}
+
+func (c *PoolClient) Complete(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool) (string, error) {
+	// the members coalesce their own requests, but identical requests would
+	// be balanced to different members
+	return c.coalesce(ctx, cacheKey(c.info, inputText, fromLanguage, toLanguage, translateOptions(ctx)), func(ctx context.Context) (string, error) {
+		return c.completeMembers(ctx, func(member Client) (string, error) {
+			return member.Complete(ctx, inputText, fromLanguage, toLanguage, forceRefresh)
+		})
+	})
+}
+
+func (c *PoolClient) CompleteStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	return c.completeMembers(ctx, func(member Client) (string, error) {
+		return member.CompleteStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, onDelta)
+	})
+}
+
+// completeMembers tries the members in turn until one of them translates.
+func (c *PoolClient) completeMembers(ctx context.Context, complete func(member Client) (string, error)) (string, error) {
+	tried := make(map[*PoolMember]bool, len(c.members))
+	var lastErr error
+	for len(tried) < len(c.members) {
+		member := c.acquire(tried)
+		tried[member] = true
+
+		translatedText, err := complete(member.Client)
+		c.release(member, err)
+		if err == nil {
+			return translatedText, nil
+		}
+		lastErr = err
+
+		// the content, the request or the caller is the problem, another member
+		// will not help, and a broken stream cannot be taken back
+		if IsRefusal(err) || ctx.Err() != nil || errors.Is(err, errStreamInterrupted) {
+			break
+		}
+		logger.Warn("Pool member failed",
+			zap.String("Name", c.info.Name),
+			zap.String("BaseURL", member.Client.GetClientInfo().BaseURL),
+			zap.Error(err),
+		)
+	}
+	return "", lastErr
+}
+
+// acquire picks the next member according to the balancing strategy, skipping
+// the ones already tried and the ones that are ejected.
+func (c *PoolClient) acquire(tried map[*PoolMember]bool) *PoolMember {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	candidates := make([]*PoolMember, 0, len(c.members))
+	for _, member := range c.members {
+		if !tried[member] && now.After(member.ejectedUntil) {
+			candidates = append(candidates, member)
+		}
+	}
+	// every remaining member is ejected, use the one that recovers first
+	if len(candidates) == 0 {
+		for _, member := range c.members {
+			if tried[member] {
+				continue
+			}
+			if len(candidates) == 0 || member.ejectedUntil.Before(candidates[0].ejectedUntil) {
+				candidates = []*PoolMember{member}
+			}
+		}
+	}
+
+	var picked *PoolMember
+	switch c.options.Strategy {
+	case BalanceLeastOutstanding:
+		for i := range candidates {
+			// start from a rotating offset so ties are spread over the members
+			member := candidates[(c.next+i)%len(candidates)]
+			if picked == nil || member.outstanding < picked.outstanding {
+				picked = member
+			}
+		}
+		c.next++
+	case BalanceWeighted:
+		// smooth weighted round robin
+		total := 0
+		for _, member := range candidates {
+			member.currentWeight += member.Weight
+			total += member.Weight
+			if picked == nil || member.currentWeight > picked.currentWeight {
+				picked = member
+			}
+		}
+		picked.currentWeight -= total
+	default:
+		picked = candidates[c.next%len(candidates)]
+		c.next++
+	}
+
+	picked.outstanding++
+	return picked
+}
+
+// release records the result of a request sent to member and ejects it after
+// too many consecutive failures.
+func (c *PoolClient) release(member *PoolMember, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	member.outstanding--
+	if !isUpstreamFailure(err) {
+		member.failures = 0
+		return
+	}
+
+	member.failures++
+	if member.failures >= c.options.MaxFailures {
+		member.failures = 0
+		member.ejectedUntil = time.Now().Add(c.options.EjectDuration)
+		logger.Warn("Pool member ejected",
+			zap.String("Name", c.info.Name),
+			zap.String("BaseURL", member.Client.GetClientInfo().BaseURL),
+			zap.Duration("EjectDuration", c.options.EjectDuration),
+		)
+	}
+}
+
+// BreakerState is closed while any member is available, half_open while one
+// of them is probing and open when all of them are down.
+func (c *PoolClient) BreakerState() string {
+	state := BreakerOpen
+	for _, member := range c.members {
+		stater, ok := member.Client.(BreakerStater)
+		if !ok {
+			return BreakerClosed
+		}
+		switch stater.BreakerState() {
+		case BreakerClosed:
+			return BreakerClosed
+		case BreakerHalfOpen:
+			state = BreakerHalfOpen
+		}
+	}
+	return state
+}
+
+func (c *PoolClient) GetClientInfo() ClientInfo {
+	return c.info
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	// maxRetryDelay caps both the backoff and the delays asked by upstreams.
+	maxRetryDelay = time.Minute
+)
+
+type RetryOptions struct {
+	MaxAttempts int           // total attempts including the first one, 0 or 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled on every attempt, 0 uses the default
+	Jitter      float64       // random fraction of the delay added or removed, between 0 and 1
+}
+
+// retryHint carries the delay an upstream asked for through the request
+// context, it is filled by retryAfterTransport.
+type retryHint struct {
+	delay time.Duration
+}
+
+type retryHintKey struct{}
+
+// retryAfterTransport records the Retry-After and rate limit reset headers of
+// throttled or failed responses into the retryHint of the request context.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500) {
+		return resp, err
+	}
+	if hint, ok := req.Context().Value(retryHintKey{}).(*retryHint); ok {
+		hint.delay = parseRetryAfter(resp.Header, time.Now())
+	}
+	return resp, err
+}
+
+// parseRetryAfter returns how long the upstream asked to wait, or 0.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("retry-after-ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return date.Sub(now)
+		}
+	}
+
+	var delay time.Duration
+	for _, key := range []string{
+		"x-ratelimit-reset",
+		"x-ratelimit-reset-requests",
+		"x-ratelimit-reset-tokens",
+		"anthropic-ratelimit-requests-reset",
+		"anthropic-ratelimit-tokens-reset",
+	} {
+		if reset := parseRateLimitReset(header.Get(key), now); reset > delay {
+			delay = reset
+		}
+	}
+	return delay
+}
+
+// parseRateLimitReset understands the formats used by the different providers:
+// seconds, unix timestamps, Go durations ("6m0s") and RFC 3339 dates.
+func parseRateLimitReset(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		if number > 1e9 {
+			return time.Unix(int64(number), 0).Sub(now)
+		}
+		return time.Duration(number * float64(time.Second))
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// isRetryable reports whether a failed upstream call is worth retrying:
+// throttling, server errors and network errors.
+func isRetryable(err error) bool {
+	if errors.Is(err, errStreamInterrupted) {
+		return false
+	}
+	if status := upstreamStatusCode(err); status != 0 {
+		return status == http.StatusTooManyRequests || status >= 500
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// backoff returns the exponential delay before the given retry with jitter.
+func (o RetryOptions) backoff(retry int) time.Duration {
+	baseDelay := o.BaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	delay := float64(baseDelay) * math.Pow(2, float64(retry-1))
+	if o.Jitter > 0 {
+		delay += delay * o.Jitter * (2*rand.Float64() - 1)
+	}
+	return min(time.Duration(delay), maxRetryDelay)
+}
+
+// throttle pushes the rate limiter into debt so that the following requests to
+// this client wait for at least delay, as asked by the upstream.
+func (c *BaseClient) throttle(delay time.Duration) {
+	if c.limiter.Limit() == rate.Inf || c.limiter.Limit() <= 0 {
+		return
+	}
+	delay = min(delay, maxRetryDelay)
+
+	c.throttleMu.Lock()
+	defer c.throttleMu.Unlock()
+	now := time.Now()
+	tokens := int(math.Ceil(c.limiter.TokensAt(now) + delay.Seconds()*float64(c.limiter.Limit())))
+	if tokens <= 0 {
+		// already in debt for longer than delay
+		return
+	}
+	// a reservation cannot take more than the burst, raise it for the time of
+	// the single reservation taking the whole debt
+	burst := c.limiter.Burst()
+	c.limiter.SetBurstAt(now, max(burst, tokens))
+	c.limiter.ReserveN(now, tokens)
+	c.limiter.SetBurstAt(now, burst)
+}
+
+// sleep waits for delay unless ctx is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
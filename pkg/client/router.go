@@ -0,0 +1,104 @@
+package client
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// Route sends the translations of a language pair to a model. From and To
+// accept language names or codes, "*" or glob patterns such as "zh*".
+type Route struct {
+	From      string
+	To        string
+	Model     string
+	Priority  int // higher priorities are tried first
+	MinLength int // minimum text length in characters, 0 means no minimum
+	MaxLength int // maximum text length in characters, 0 means no maximum
+}
+
+func (r Route) matches(fromCode string, toCode string, length int) bool {
+	if r.MinLength > 0 && length < r.MinLength {
+		return false
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		return false
+	}
+	return languageMatches(r.From, fromCode) && languageMatches(r.To, toCode)
+}
+
+// languageMatches matches a language code against a route pattern. A pattern
+// without a region, e.g. "zh", matches every region of the language.
+func languageMatches(pattern string, code string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if base, _, _ := strings.Cut(code, "-"); strings.EqualFold(pattern, base) {
+		return true
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = LanguageCode(pattern)
+	}
+	matched, err := path.Match(pattern, code)
+	return err == nil && matched
+}
+
+// estimateTokens roughly estimates the number of tokens of a text: about four
+// characters per token for ASCII and one token per character otherwise.
+func estimateTokens(text string) int {
+	ascii := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] < utf8.RuneSelf {
+			ascii++
+		}
+	}
+	return ascii/4 + utf8.RuneCountInString(text) - ascii
+}
+
+// SetRoutes sets the routing table used by Route.
+func (m *ClientManager) SetRoutes(routes []Route) {
+	sorted := slices.Clone(routes)
+	slices.SortStableFunc(sorted, func(a, b Route) int {
+		return b.Priority - a.Priority
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = sorted
+}
+
+// Route picks the model for a translation by language pair and text length,
+// skipping the models whose max tokens are too small for the text.
+func (m *ClientManager) Route(inputText string, fromLanguage string, toLanguage string) (Client, error) {
+	fromCode := LanguageCode(fromLanguage)
+	toCode := LanguageCode(toLanguage)
+	length := utf8.RuneCountInString(inputText)
+	tokens := estimateTokens(inputText)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, route := range m.routes {
+		if !route.matches(fromCode, toCode, length) {
+			continue
+		}
+		client, ok := m.clientsWithName[route.Model]
+		if !ok {
+			continue
+		}
+		if maxTokens := client.GetClientInfo().MaxTokens; maxTokens > 0 && tokens > maxTokens {
+			logger.Debug("Text too long for routed model",
+				zap.String("Model", route.Model),
+				zap.Int("Tokens", tokens),
+				zap.Int("MaxTokens", maxTokens),
+			)
+			continue
+		}
+		return client, nil
+	}
+	logger.Error("No route found", zap.String("From", fromLanguage), zap.String("To", toLanguage), zap.Int("Length", length))
+	return nil, fmt.Errorf("no route found from %s to %s", fromLanguage, toLanguage)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// errStreamInterrupted wraps the errors of a streamed translation that
+	// failed after part of it was passed on, it can neither be retried nor
+	// fall back to another client without repeating that part.
+	errStreamInterrupted = errors.New("stream interrupted")
+	// errDeltaRejected wraps the errors returned by the receiver of a streamed
+	// translation, e.g. when the caller went away.
+	errDeltaRejected = errors.New("delta rejected")
+)
+
+// DeltaFunc receives the pieces of a streamed translation as they arrive,
+// returning an error aborts the translation.
+type DeltaFunc func(delta string) error
+
+// streamFunc performs the actual upstream call for a single translation,
+// passing the pieces of the translation to onDelta as they arrive. It returns
+// the whole translation.
+type streamFunc func(ctx context.Context, inputText string, fromLanguage string, toLanguage string, onDelta DeltaFunc) (string, error)
+
+// wholeStream adapts the upstream call of a client that cannot stream, the
+// whole translation is passed to onDelta at once.
+func wholeStream(call completeFunc) streamFunc {
+	return func(ctx context.Context, inputText string, fromLanguage string, toLanguage string, onDelta DeltaFunc) (string, error) {
+		content, err := call(ctx, inputText, fromLanguage, toLanguage)
+		if err != nil {
+			return "", err
+		}
+		return content, onDelta(content)
+	}
+}
+
+// completeStream is the streaming counterpart of complete. A cached
+// translation is passed to onDelta at once, otherwise the assembled
+// translation is cached when the stream completes. Streamed requests are not
+// coalesced since every caller needs its own deltas.
+func (c *BaseClient) completeStream(ctx context.Context, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, stream streamFunc, onDelta DeltaFunc) (string, error) {
+	cacheKey := cacheKey(c.info, inputText, fromLanguage, toLanguage, translateOptions(ctx))
+
+	if !forceRefresh {
+		if cached, err := c.cache.Get(cacheKey); err == nil {
+			logger.Debug("Cache hit", zap.String("Key", cacheKey))
+			record := decodeCacheRecord(cached)
+			if record.Refusal != nil {
+				return "", &RefusedError{Refusal: *record.Refusal, Cached: true, Err: record.Refusal.err()}
+			}
+			if err := onDelta(record.Translation); err != nil {
+				return "", fmt.Errorf("%w: %w: %w", errStreamInterrupted, errDeltaRejected, err)
+			}
+			return record.Translation, nil
+		}
+	}
+
+	return c.callAndCache(ctx, cacheKey, inputText, fromLanguage, toLanguage, func(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, error) {
+		streamed := false
+		content, err := stream(ctx, inputText, fromLanguage, toLanguage, func(delta string) error {
+			streamed = true
+			if err := onDelta(delta); err != nil {
+				return fmt.Errorf("%w: %w", errDeltaRejected, err)
+			}
+			return nil
+		})
+		if err != nil && streamed {
+			return "", fmt.Errorf("%w: %w", errStreamInterrupted, err)
+		}
+		return content, err
+	})
+}
+
+// CompleteStream is the streaming counterpart of Complete. The fallback clients
+// are only tried as long as nothing has been passed to onDelta, hedging is
+// not applied.
+func (m *ClientManager) CompleteStream(ctx context.Context, primary Client, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, Client, error) {
+	if !forceRefresh {
+		var translatedText string
+		var found bool
+		if translatedText, ctx, found = m.searchMemory(ctx, inputText, fromLanguage, toLanguage); found {
+			if err := onDelta(translatedText); err != nil {
+				return "", primary, err
+			}
+			return translatedText, primary, nil
+		}
+	}
+
+	chain := m.chain(primary)
+	var lastErr error
+	for i, client := range chain {
+		translatedText, err := completeStreamWithTimeout(ctx, client, inputText, fromLanguage, toLanguage, forceRefresh, onDelta)
+		if err == nil {
+			if i > 0 {
+				logger.Info("Translated by fallback client",
+					zap.String("Name", primary.GetClientInfo().Name),
+					zap.String("Fallback", client.GetClientInfo().Name),
+				)
+			}
+			m.learn(inputText, fromLanguage, toLanguage, translatedText)
+			return translatedText, client, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil || errors.Is(err, errStreamInterrupted) {
+			break
+		}
+		if i < len(chain)-1 {
+			logger.Warn("Client failed, trying fallback",
+				zap.String("Name", client.GetClientInfo().Name),
+				zap.String("Fallback", chain[i+1].GetClientInfo().Name),
+				zap.Error(err),
+			)
+		}
+	}
+	return "", primary, lastErr
+}
+
+func completeStreamWithTimeout(ctx context.Context, client Client, inputText string, fromLanguage string, toLanguage string, forceRefresh bool, onDelta DeltaFunc) (string, error) {
+	if timeout := client.GetClientInfo().Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return client.CompleteStream(ctx, inputText, fromLanguage, toLanguage, forceRefresh, onDelta)
+}
@@ -0,0 +1,333 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxFuzzyCandidates is the number of segments sharing the most trigrams
+	// with the text that are scored by edit distance.
+	maxFuzzyCandidates = 50
+	// maxEditDistanceLength is the length above which the trigram score is
+	// used instead of the quadratic edit distance.
+	maxEditDistanceLength = 1000
+)
+
+// TMSegment is a source segment and its translation. The languages are
+// language codes, see LanguageCode.
+type TMSegment struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// TMMatch is a segment similar to a searched text, a score of 1 being an
+// exact match.
+type TMMatch struct {
+	TMSegment
+	Score float64 `json:"score"`
+}
+
+type tmPair struct {
+	from string
+	to   string
+}
+
+type tmEntry struct {
+	segment    TMSegment
+	normalized string
+	trigrams   int
+}
+
+// tmIndex holds the segments of a language pair, indexed by normalized
+// source and by trigram.
+type tmIndex struct {
+	entries []tmEntry
+	exact   map[string]int
+	grams   map[string][]int
+}
+
+// TranslationMemory stores the translated segments per language pair and
+// finds the exact and fuzzy matches of a text.
+type TranslationMemory struct {
+	pairs map[tmPair]*tmIndex
+	mu    sync.RWMutex
+}
+
+func NewTranslationMemory() *TranslationMemory {
+	return &TranslationMemory{pairs: make(map[tmPair]*tmIndex)}
+}
+
+// Add stores a segment, replacing the translation of an identical source.
+func (tm *TranslationMemory) Add(fromLanguage string, toLanguage string, source string, target string) {
+	// the segment and its pair outlive the request the strings may point into
+	pair := tmPair{from: strings.Clone(tmLanguage(fromLanguage)), to: strings.Clone(tmLanguage(toLanguage))}
+	source, target = strings.Clone(source), strings.Clone(target)
+	normalized := normalizeSegment(source)
+	if normalized == "" || strings.TrimSpace(target) == "" {
+		return
+	}
+	segment := TMSegment{From: pair.from, To: pair.to, Source: source, Target: target}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	index, ok := tm.pairs[pair]
+	if !ok {
+		index = &tmIndex{exact: make(map[string]int), grams: make(map[string][]int)}
+		tm.pairs[pair] = index
+	}
+	if i, ok := index.exact[normalized]; ok {
+		index.entries[i].segment = segment
+		return
+	}
+
+	grams := trigrams(normalized)
+	i := len(index.entries)
+	index.entries = append(index.entries, tmEntry{segment: segment, normalized: normalized, trigrams: len(grams)})
+	index.exact[normalized] = i
+	for gram := range grams {
+		index.grams[gram] = append(index.grams[gram], i)
+	}
+}
+
+// Len returns the number of segments.
+func (tm *TranslationMemory) Len() int {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	total := 0
+	for _, index := range tm.pairs {
+		total += len(index.entries)
+	}
+	return total
+}
+
+// Segments returns a copy of all the segments.
+func (tm *TranslationMemory) Segments() []TMSegment {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var segments []TMSegment
+	for _, index := range tm.pairs {
+		for _, entry := range index.entries {
+			segments = append(segments, entry.segment)
+		}
+	}
+	return segments
+}
+
+// Search returns at most limit segments whose source is at least threshold
+// similar to the text, best first. An automatically detected source language
+// searches every source language of the target language.
+func (tm *TranslationMemory) Search(inputText string, fromLanguage string, toLanguage string, limit int, threshold float64) []TMMatch {
+	normalized := normalizeSegment(inputText)
+	if normalized == "" || limit <= 0 {
+		return nil
+	}
+	fromCode, toCode := tmLanguage(fromLanguage), tmLanguage(toLanguage)
+	grams := trigrams(normalized)
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var matches []TMMatch
+	for pair, index := range tm.pairs {
+		if pair.to != toCode || (fromCode != "" && pair.from != fromCode) {
+			continue
+		}
+		if i, ok := index.exact[normalized]; ok {
+			matches = append(matches, TMMatch{TMSegment: index.entries[i].segment, Score: 1})
+			continue
+		}
+		matches = append(matches, index.fuzzy(normalized, grams, threshold)...)
+	}
+
+	slices.SortStableFunc(matches, func(a, b TMMatch) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// fuzzy scores the segments sharing the most trigrams with the text by edit
+// distance.
+func (index *tmIndex) fuzzy(normalized string, grams map[string]struct{}, threshold float64) []TMMatch {
+	shared := make(map[int]int)
+	for gram := range grams {
+		for _, i := range index.grams[gram] {
+			shared[i]++
+		}
+	}
+
+	type candidate struct {
+		entry int
+		dice  float64
+	}
+	candidates := make([]candidate, 0, len(shared))
+	for i, count := range shared {
+		dice := 2 * float64(count) / float64(len(grams)+index.entries[i].trigrams)
+		// the edit distance score is rarely much higher than the trigram one
+		if dice >= threshold/2 {
+			candidates = append(candidates, candidate{entry: i, dice: dice})
+		}
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(b.dice, a.dice)
+	})
+	if len(candidates) > maxFuzzyCandidates {
+		candidates = candidates[:maxFuzzyCandidates]
+	}
+
+	var matches []TMMatch
+	for _, candidate := range candidates {
+		entry := index.entries[candidate.entry]
+		score := candidate.dice
+		if utf8.RuneCountInString(normalized) <= maxEditDistanceLength && utf8.RuneCountInString(entry.normalized) <= maxEditDistanceLength {
+			score = editSimilarity(normalized, entry.normalized)
+		}
+		if score >= threshold {
+			matches = append(matches, TMMatch{TMSegment: entry.segment, Score: score})
+		}
+	}
+	return matches
+}
+
+// tmLanguage is the language code the segments are stored under. Regions are
+// dropped unless they are part of a canonical code, e.g. "fr-CA" is "fr" but
+// "zh-TW" stays "zh-TW".
+func tmLanguage(language string) string {
+	code := LanguageCode(language)
+	if base, _, found := strings.Cut(code, "-"); found && code == strings.ToLower(code) {
+		return LanguageCode(base)
+	}
+	return code
+}
+
+func normalizeSegment(text string) string {
+	return strings.ToLower(NormalizeText(text))
+}
+
+// trigrams returns the distinct character trigrams of a text, or the text
+// itself when it is shorter than three characters.
+func trigrams(text string) map[string]struct{} {
+	runes := []rune(text)
+	grams := make(map[string]struct{})
+	if len(runes) < 3 {
+		grams[text] = struct{}{}
+		return grams
+	}
+	for i := 0; i+3 <= len(runes); i++ {
+		grams[string(runes[i:i+3])] = struct{}{}
+	}
+	return grams
+}
+
+// editSimilarity is 1 minus the Levenshtein distance of the texts divided by
+// the length of the longest one.
+func editSimilarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+type tmReferencesKey struct{}
+
+// withReferences attaches the fuzzy matches of a text to the context of its
+// translation, the LLM clients add them to their prompt as examples.
+func withReferences(ctx context.Context, matches []TMMatch) context.Context {
+	return context.WithValue(ctx, tmReferencesKey{}, matches)
+}
+
+// referencesPrompt renders the fuzzy matches attached to the context.
+func referencesPrompt(ctx context.Context) string {
+	matches, _ := ctx.Value(tmReferencesKey{}).([]TMMatch)
+	if len(matches) == 0 {
+		return ""
+	}
+	var prompt strings.Builder
+	prompt.WriteString("\n\nTranslations of similar texts, for reference:")
+	for _, match := range matches {
+		fmt.Fprintf(&prompt, "\n- %q => %q", match.Source, match.Target)
+	}
+	return prompt.String()
+}
+
+type TMOptions struct {
+	Threshold   float64 // minimum similarity of the fuzzy matches, between 0 and 1
+	MaxExamples int     // fuzzy matches added to the prompt
+	Learn       bool    // whether the translations of the gateway are added to the memory
+}
+
+// SetTranslationMemory makes Complete answer the exact matches of the memory
+// and send its fuzzy matches to the models as references.
+func (m *ClientManager) SetTranslationMemory(tm *TranslationMemory, options TMOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tm = tm
+	m.tmOptions = options
+}
+
+// TranslationMemory returns the translation memory, or nil when there is none.
+func (m *ClientManager) TranslationMemory() *TranslationMemory {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tm
+}
+
+// searchMemory returns the translation of an exact match of the memory, or
+// attaches its fuzzy matches to the context.
+func (m *ClientManager) searchMemory(ctx context.Context, inputText string, fromLanguage string, toLanguage string) (string, context.Context, bool) {
+	m.mu.RLock()
+	tm, options := m.tm, m.tmOptions
+	m.mu.RUnlock()
+	if tm == nil {
+		return "", ctx, false
+	}
+
+	matches := tm.Search(inputText, fromLanguage, toLanguage, max(options.MaxExamples, 1), options.Threshold)
+	if len(matches) > 0 && matches[0].Score == 1 {
+		logger.Debug("Translation memory hit", zap.String("From", fromLanguage), zap.String("To", toLanguage))
+		return matches[0].Target, ctx, true
+	}
+	if options.MaxExamples > 0 && len(matches) > 0 {
+		logger.Debug("Translation memory references", zap.Int("Matches", len(matches)))
+		ctx = withReferences(ctx, matches)
+	}
+	return "", ctx, false
+}
+
+func (m *ClientManager) learn(inputText string, fromLanguage string, toLanguage string, translatedText string) {
+	m.mu.RLock()
+	tm, learn := m.tm, m.tmOptions.Learn
+	m.mu.RUnlock()
+	// a detected source language is unknown
+	if tm != nil && learn && LanguageCode(fromLanguage) != "" {
+		tm.Add(fromLanguage, toLanguage, inputText, translatedText)
+	}
+}
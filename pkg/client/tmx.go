@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type tmxDocument struct {
+	Header struct {
+		SrcLang string `xml:"srclang,attr"`
+	} `xml:"header"`
+	Units []tmxUnit `xml:"body>tu"`
+}
+
+type tmxUnit struct {
+	SrcLang      string       `xml:"srclang,attr"`
+	CreationDate string       `xml:"creationdate,attr"`
+	CreationID   string       `xml:"creationid,attr"`
+	Props        []tmxProp    `xml:"prop"`
+	Variants     []tmxVariant `xml:"tuv"`
+}
+
+type tmxProp struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type tmxVariant struct {
+	Lang    string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	OldLang string     `xml:"lang,attr"` // TMX 1.1
+	Segment tmxSegment `xml:"seg"`
+}
+
+func (v tmxVariant) language() string {
+	if v.Lang != "" {
+		return v.Lang
+	}
+	return v.OldLang
+}
+
+// tmxSegment is the text of a segment without its inline codes.
+type tmxSegment string
+
+// tmxInlineCodes are the elements holding the native codes of a segment,
+// their content is not text.
+var tmxInlineCodes = []string{"bpt", "ept", "it", "ph", "ut"}
+
+func (s *tmxSegment) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var text strings.Builder
+	depth, codeDepth := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			if codeDepth == 0 && slices.Contains(tmxInlineCodes, token.Name.Local) {
+				codeDepth = depth
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				*s = tmxSegment(text.String())
+				return nil
+			}
+			if depth == codeDepth {
+				codeDepth = 0
+			}
+			depth--
+		case xml.CharData:
+			if codeDepth == 0 {
+				text.Write(token)
+			}
+		}
+	}
+}
+
+// decodeTMX calls fn with every variant of a unit and its source variant,
+// every other variant being a source when the source language is "*all*".
+func decodeTMX(reader io.Reader, fn func(unit tmxUnit, source tmxVariant, target tmxVariant) error) error {
+	var document tmxDocument
+	if err := xml.NewDecoder(reader).Decode(&document); err != nil {
+		return fmt.Errorf("invalid tmx: %w", err)
+	}
+
+	for _, unit := range document.Units {
+		srcLang := unit.SrcLang
+		if srcLang == "" {
+			srcLang = document.Header.SrcLang
+		}
+		for _, source := range unit.Variants {
+			if srcLang != "*all*" && !strings.EqualFold(source.language(), srcLang) {
+				continue
+			}
+			for _, target := range unit.Variants {
+				if strings.EqualFold(source.language(), target.language()) {
+					continue
+				}
+				if err := fn(unit, source, target); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ImportTMX adds the translation units of a TMX document to the memory.
+func (tm *TranslationMemory) ImportTMX(reader io.Reader) (int, error) {
+	imported := 0
+	err := decodeTMX(reader, func(unit tmxUnit, source tmxVariant, target tmxVariant) error {
+		tm.Add(source.language(), target.language(), string(source.Segment), string(target.Segment))
+		imported++
+		return nil
+	})
+	return imported, err
+}